@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/zhrkvl/ralph-go/internal/api"
+	"github.com/zhrkvl/ralph-go/internal/tui"
+)
+
+var (
+	attachReadOnlyFlag bool
+	attachAddrFlag     string
+)
+
+func newAttachCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "attach",
+		Short: "Open the TUI onto a session running in another ralph",
+		Long: "Attach connects to the API of the ralph running in the project, as recorded in\n" +
+			".ralph-tui/session.json, and shows its dashboard: recent agent output, then new\n" +
+			"output as it comes. The control keys (p, s, c, g, x) steer that session; q detaches\n" +
+			"and leaves it running. --read-only leaves out the control keys.",
+		Args: cobra.NoArgs,
+		RunE: attachCmd,
+	}
+	cmd.Flags().BoolVar(&attachReadOnlyFlag, "read-only", false, "watch the session without controlling it")
+	cmd.Flags().StringVar(&attachAddrFlag, "addr", "", "API address to attach to, as --listen takes it (default: from the session)")
+	cmd.Flags().StringVar(&projectDirFlag, "project-dir", "", "project directory (default: CWD)")
+	return cmd
+}
+
+func attachCmd(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+	projectDir := projectDirFlag
+	if projectDir == "" {
+		var err error
+		if projectDir, err = os.Getwd(); err != nil {
+			return fmt.Errorf("getting CWD: %w", err)
+		}
+	}
+	projectDir, _ = filepath.Abs(projectDir)
+
+	addr := attachAddrFlag
+	if addr == "" {
+		sess, _, st, err := liveSession(projectDir)
+		if err != nil {
+			return err
+		}
+		if sess.Server == "" {
+			return fmt.Errorf("session %s was started without the API; start ralph with --listen or --detach to attach to it", sess.SessionID)
+		}
+		if st == nil {
+			return fmt.Errorf("ralph is not running in %s (session %s is %s)", projectDir, sess.SessionID, sess.Status)
+		}
+		addr = sess.Server
+	}
+	return tui.Attach(tui.AttachOptions{
+		Client:   api.NewClient(addr, projectDir),
+		Addr:     addr,
+		ReadOnly: attachReadOnlyFlag,
+	})
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mattn/go-isatty"
+	"github.com/spf13/cobra"
+	"github.com/zhrkvl/ralph-go/internal/config"
+)
+
+var originFlag bool
+
+func newConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the configuration",
+	}
+
+	show := &cobra.Command{
+		Use:   "show",
+		Short: "Print the effective configuration",
+		Long: "Show prints every setting as ralph would use it. Each layer overrides the one\n" +
+			"before: built-in defaults, " + config.GlobalPath() + ",\n" +
+			".ralph-tui/config.toml in the project, RALPH_* environment variables and flags.",
+		Args: cobra.NoArgs,
+		RunE: showConfig,
+	}
+	show.Flags().BoolVar(&originFlag, "origin", false, "show where each value came from")
+	show.Flags().StringVar(&projectDirFlag, "project-dir", "", "project directory (default: CWD)")
+	show.Flags().StringVar(&toolFlag, "tool", "", "apply --tool as ralph would")
+	show.Flags().StringVar(&modelFlag, "model", "", "apply --model as ralph would")
+	show.Flags().IntVar(&maxIterFlag, "max-iterations", 0, "apply --max-iterations as ralph would")
+	show.Flags().BoolVar(&stepFlag, "step", false, "apply --step as ralph would")
+	show.Flags().StringVar(&profileFlag, "profile", "", "apply --profile as ralph would")
+	cmd.AddCommand(show)
+
+	migrate := &cobra.Command{
+		Use:   "migrate",
+		Short: "Rewrite config files from an older ralph in the current format",
+		Long: "Migrate updates the global and project config files written by an older ralph\n" +
+			"to configVersion " + config.Version + ". The original is kept as config.toml.bak.",
+		Args: cobra.NoArgs,
+		RunE: migrateConfig,
+	}
+	migrate.Flags().StringVar(&projectDirFlag, "project-dir", "", "project directory (default: CWD)")
+	cmd.AddCommand(migrate)
+	return cmd
+}
+
+func showConfig(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+	projectDir := projectDirFlag
+	if projectDir == "" {
+		var err error
+		if projectDir, err = os.Getwd(); err != nil {
+			return fmt.Errorf("getting CWD: %w", err)
+		}
+	}
+	projectDir, _ = filepath.Abs(projectDir)
+
+	cfg, err := loadConfig(projectDir)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, v := range cfg.Values() {
+		if isSecret(v.Key) && v.Value != `""` {
+			v.Value = `"********"`
+		}
+		if originFlag {
+			fmt.Fprintf(w, "%s = %s\t# %s\n", v.Key, v.Value, v.Origin)
+		} else {
+			fmt.Fprintf(w, "%s = %s\n", v.Key, v.Value)
+		}
+	}
+	return w.Flush()
+}
+
+func migrateConfig(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+	projectDir := projectDirFlag
+	if projectDir == "" {
+		var err error
+		if projectDir, err = os.Getwd(); err != nil {
+			return fmt.Errorf("getting CWD: %w", err)
+		}
+	}
+	projectDir, _ = filepath.Abs(projectDir)
+
+	cfg, err := config.Load(projectDir)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	if len(cfg.Migrations()) == 0 {
+		fmt.Printf("Config is up to date (configVersion %s)\n", config.Version)
+		return nil
+	}
+	for _, m := range cfg.Migrations() {
+		if err := m.Apply(); err != nil {
+			return fmt.Errorf("migrating %s: %w", m.Path, err)
+		}
+		fmt.Printf("Migrated %s from %s to %s\n", m.Path, m.From, m.To)
+	}
+	return nil
+}
+
+// offerMigration describes a pending config migration and, when ralph runs
+// in a terminal, asks whether to rewrite the file. The old file keeps
+// working either way: Load already reads it as migrated. Only `ralph run`
+// offers it; other commands read the file quietly.
+func offerMigration(m *config.Migration) {
+	fmt.Fprintf(os.Stderr, "%s was written for configVersion %s; this ralph uses %s:\n", m.Path, m.From, m.To)
+	for _, c := range m.Changes {
+		fmt.Fprintf(os.Stderr, "  %s\n", c)
+	}
+	if !isatty.IsTerminal(os.Stdin.Fd()) {
+		fmt.Fprintf(os.Stderr, "Run `ralph config migrate` to update it.\n")
+		return
+	}
+	kept := "Comments are not kept; the"
+	if m.KeepsComments() {
+		kept = "The"
+	}
+	fmt.Fprintf(os.Stderr, "Rewrite it now? %s original is saved as %s.bak [y/N] ", kept, filepath.Base(m.Path))
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+		return
+	}
+	if err := m.Apply(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: migrating %s: %v\n", m.Path, err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Migrated %s\n", m.Path)
+}
+
+// isSecret reports whether a key holds a credential that should not be
+// printed, such as trackerOptions.github.token.
+func isSecret(key string) bool {
+	name := strings.ToLower(key[strings.LastIndex(key, ".")+1:])
+	for _, s := range []string{"token", "secret", "password"} {
+		if strings.Contains(name, s) && !strings.HasSuffix(name, "env") {
+			return true
+		}
+	}
+	return false
+}
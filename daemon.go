@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/zhrkvl/ralph-go/internal/api"
+)
+
+// daemonStartTimeout is how long `ralph --detach` waits for the daemon's
+// API to answer.
+const daemonStartTimeout = 30 * time.Second
+
+// detachListen is where a daemon serves its API when server.listen is
+// not set.
+const detachListen = "unix:.ralph-tui/ralph.sock"
+
+// startDaemon starts the loop again in a session of its own, detached
+// from the terminal, with its output going to .ralph-tui/daemon.log. It
+// returns once the daemon's API answers, or with the log of a daemon that
+// failed to start.
+func startDaemon(projectDir string) error {
+	if pid, err := readPIDFile(projectDir); err == nil && processAlive(pid) {
+		return fmt.Errorf("ralph is already running in the background in %s (pid %d); see ralph attach and ralph stop", projectDir, pid)
+	}
+
+	dir := filepath.Join(projectDir, ".ralph-tui")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	logPath := filepath.Join(dir, "daemon.log")
+	log, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	defer log.Close()
+	logStart, _ := log.Seek(0, io.SeekEnd)
+	fmt.Fprintf(log, "=== ralph started %s\n", time.Now().Format(time.RFC3339))
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	var args []string
+	for _, a := range os.Args[1:] {
+		if a != "--detach" && !strings.HasPrefix(a, "--detach=") {
+			args = append(args, a)
+		}
+	}
+	cmd := exec.Command(exe, append(args, "--daemon")...)
+	cmd.Stdout, cmd.Stderr = log, log
+	// A session of its own, so closing the terminal does not reach it
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("starting ralph in the background: %w", err)
+	}
+	pid := cmd.Process.Pid
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	deadline := time.After(daemonStartTimeout)
+	tick := time.NewTicker(200 * time.Millisecond)
+	defer tick.Stop()
+	for {
+		select {
+		case err := <-exited:
+			if err == nil {
+				err = errors.New("exited")
+			}
+			return fmt.Errorf("ralph stopped at start (%v); %s says:\n%s", err, logPath, readLogFrom(logPath, logStart))
+		case <-deadline:
+			return fmt.Errorf("ralph (pid %d) is not answering after %s; see %s", pid, daemonStartTimeout, logPath)
+		case <-tick.C:
+		}
+		if p, err := readPIDFile(projectDir); err != nil || p != pid {
+			continue
+		}
+		if sess, _, st, err := liveSession(projectDir); err == nil && st != nil {
+			fmt.Printf("ralph is running in the background (pid %d, session %s).\n", pid, sess.SessionID)
+			fmt.Println("  ralph attach   open the TUI onto it")
+			fmt.Println("  ralph status   show its progress")
+			fmt.Println("  ralph stop     stop it")
+			fmt.Printf("Output is logged to %s\n", logPath)
+			return nil
+		}
+	}
+}
+
+// readLogFrom returns what was written to the log after offset.
+func readLogFrom(path string, offset int64) string {
+	data, err := os.ReadFile(path)
+	if err != nil || offset > int64(len(data)) {
+		return ""
+	}
+	return strings.TrimRight(string(data[offset:]), "\n")
+}
+
+func pidFilePath(projectDir string) string {
+	return filepath.Join(projectDir, ".ralph-tui", "ralph.pid")
+}
+
+// writePIDFile records the daemon's process ID for `ralph stop`.
+func writePIDFile(projectDir string) error {
+	return os.WriteFile(pidFilePath(projectDir), []byte(strconv.Itoa(os.Getpid())+"\n"), 0o644)
+}
+
+// removePIDFile removes the PID file if it is still this process's.
+func removePIDFile(projectDir string) {
+	if pid, err := readPIDFile(projectDir); err == nil && pid == os.Getpid() {
+		os.Remove(pidFilePath(projectDir))
+	}
+}
+
+func readPIDFile(projectDir string) (int, error) {
+	data, err := os.ReadFile(pidFilePath(projectDir))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(data)))
+}
+
+func processAlive(pid int) bool {
+	return syscall.Kill(pid, 0) == nil
+}
+
+var stopTimeoutFlag time.Duration
+
+func newStopCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "stop",
+		Short: "Stop the session running in a project",
+		Long: "Stop ends the session of the ralph running in the project, in the background or in\n" +
+			"another terminal, as quitting its TUI does: the agent is killed, the session is\n" +
+			"saved as interrupted and the sessionEnd hooks run. It waits for ralph to exit.",
+		Args: cobra.NoArgs,
+		RunE: stopCmd,
+	}
+	cmd.Flags().DurationVar(&stopTimeoutFlag, "timeout", 30*time.Second, "how long to wait for ralph to exit")
+	cmd.Flags().StringVar(&projectDirFlag, "project-dir", "", "project directory (default: CWD)")
+	return cmd
+}
+
+func stopCmd(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+	projectDir := projectDirFlag
+	if projectDir == "" {
+		var err error
+		if projectDir, err = os.Getwd(); err != nil {
+			return fmt.Errorf("getting CWD: %w", err)
+		}
+	}
+	projectDir, _ = filepath.Abs(projectDir)
+
+	pid, _ := readPIDFile(projectDir)
+	if pid != 0 && !processAlive(pid) {
+		os.Remove(pidFilePath(projectDir))
+		pid = 0
+	}
+	sess, c, st, err := liveSession(projectDir)
+	if err != nil && pid == 0 {
+		return err
+	}
+	switch {
+	case st != nil:
+		if _, err := c.Control(context.Background(), api.Stop, ""); err != nil {
+			return fmt.Errorf("stopping session %s: %w", sess.SessionID, err)
+		}
+		fmt.Printf("Stopping session %s...\n", sess.SessionID)
+	case pid != 0:
+		// The daemon does not answer; it stops the same way on SIGTERM.
+		if err := syscall.Kill(pid, syscall.SIGTERM); err != nil {
+			return fmt.Errorf("stopping ralph (pid %d): %w", pid, err)
+		}
+		fmt.Printf("Stopping ralph (pid %d)...\n", pid)
+	default:
+		return fmt.Errorf("ralph is not running in %s", projectDir)
+	}
+
+	// A daemon is gone when its process is; ralph in a terminal, when its
+	// API stops answering.
+	stopped := func() bool {
+		if pid != 0 {
+			return !processAlive(pid)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_, err := c.Status(ctx)
+		return err != nil
+	}
+	deadline := time.Now().Add(stopTimeoutFlag)
+	for !stopped() {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("ralph has not exited after %s", stopTimeoutFlag)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	fmt.Println("Stopped.")
+	return nil
+}
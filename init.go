@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/mattn/go-isatty"
+	"github.com/spf13/cobra"
+	"github.com/zhrkvl/ralph-go/internal/agent"
+	"github.com/zhrkvl/ralph-go/internal/plan"
+	"github.com/zhrkvl/ralph-go/internal/scaffold"
+)
+
+var (
+	initAgentFlag  string
+	initBranchFlag string
+	initNameFlag   string
+	initGateFlags  []string
+	initForceFlag  bool
+)
+
+func newInitCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "init",
+		Short: "Set up a project for ralph from built-in templates",
+		Long: "Init writes CLAUDE.md, prompt.md, a sample prd.json and progress.txt to the ralph\n" +
+			"directory, and .ralph-tui/config.toml to the project. The templates are built into\n" +
+			"ralph, so nothing is downloaded. Settings not given as flags are asked for when\n" +
+			"running in a terminal. Existing files are only overwritten with --force.",
+		Args: cobra.NoArgs,
+		RunE: initProject,
+	}
+	cmd.Flags().StringVar(&initAgentFlag, "agent", "claude", "agent to configure: "+strings.Join(agent.Names, ", "))
+	cmd.Flags().StringVar(&initBranchFlag, "branch", "", "branchName for the PRD (default: ralph/<project name>)")
+	cmd.Flags().StringVar(&initNameFlag, "name", "", "project name (default: the project directory's name)")
+	cmd.Flags().StringArrayVar(&initGateFlags, "gate", nil, "quality check command the agent must pass before committing (repeatable)")
+	cmd.Flags().StringVar(&ralphDirFlag, "ralph-dir", "", "directory for the prompt files and PRD (default: scripts/ralph)")
+	cmd.Flags().StringVar(&projectDirFlag, "project-dir", "", "project directory (default: CWD)")
+	cmd.Flags().BoolVar(&initForceFlag, "force", false, "overwrite existing files")
+	return cmd
+}
+
+func initProject(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+	projectDir := projectDirFlag
+	if projectDir == "" {
+		var err error
+		if projectDir, err = os.Getwd(); err != nil {
+			return fmt.Errorf("getting CWD: %w", err)
+		}
+	}
+	projectDir, _ = filepath.Abs(projectDir)
+	ralphDir := ralphDirFlag
+	if ralphDir == "" {
+		ralphDir = filepath.Join(projectDir, "scripts", "ralph")
+	}
+	ralphDir, _ = filepath.Abs(ralphDir)
+
+	opts := scaffold.Options{
+		RalphDir:   ralphDir,
+		ProjectDir: projectDir,
+		Project:    initNameFlag,
+		Agent:      initAgentFlag,
+		Branch:     initBranchFlag,
+		Gates:      initGateFlags,
+	}
+	if opts.Project == "" {
+		opts.Project = filepath.Base(projectDir)
+	}
+
+	// Check before asking anything, so a re-run fails fast.
+	if existing := scaffold.Existing(opts); len(existing) > 0 && !initForceFlag {
+		return fmt.Errorf("not overwriting %s (use --force)", strings.Join(existing, ", "))
+	}
+
+	if isatty.IsTerminal(os.Stdin.Fd()) {
+		in := bufio.NewReader(os.Stdin)
+		flags := cmd.Flags()
+		if !flags.Changed("agent") {
+			opts.Agent = ask(in, "Agent ("+strings.Join(agent.Names, ", ")+")", opts.Agent)
+		}
+		if !flags.Changed("branch") {
+			opts.Branch = ask(in, "Branch name", plan.BranchName(opts.Project))
+		}
+		if !flags.Changed("gate") {
+			for _, g := range strings.Split(ask(in, "Quality check commands, separated by ;", ""), ";") {
+				if g = strings.TrimSpace(g); g != "" {
+					opts.Gates = append(opts.Gates, g)
+				}
+			}
+		}
+	}
+	if !slices.Contains(agent.Names, opts.Agent) {
+		return fmt.Errorf("invalid agent '%s'. Must be one of: %s", opts.Agent, strings.Join(agent.Names, ", "))
+	}
+	if opts.Branch == "" {
+		opts.Branch = plan.BranchName(opts.Project)
+	}
+
+	written, err := scaffold.Init(opts)
+	for _, path := range written {
+		rel, _ := filepath.Rel(projectDir, path)
+		fmt.Printf("Wrote %s\n", rel)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\nEdit the stories in %s, then run ralph.\n", filepath.Join(ralphDir, "prd.json"))
+	return nil
+}
+
+// ask prompts for a value on stderr and returns def if the answer is empty.
+func ask(in *bufio.Reader, prompt, def string) string {
+	if def != "" {
+		fmt.Fprintf(os.Stderr, "%s [%s]: ", prompt, def)
+	} else {
+		fmt.Fprintf(os.Stderr, "%s: ", prompt)
+	}
+	answer, _ := in.ReadString('\n')
+	if answer = strings.TrimSpace(answer); answer != "" {
+		return answer
+	}
+	return def
+}
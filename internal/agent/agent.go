@@ -1,14 +1,19 @@
 package agent
 
-import "context"
+import (
+	"context"
+
+	"github.com/zhrkvl/ralph-go/internal/prd"
+)
 
 // Agent represents an AI agent that can be started, paused, and stopped.
 type Agent interface {
 	// Start launches the agent subprocess. Output is streamed line-by-line
 	// to the returned channel. The channel closes when the process exits.
-	Start(ctx context.Context) (<-chan string, error)
+	Start(ctx context.Context) (<-chan Line, error)
 
-	// Wait blocks until the subprocess exits and returns the full combined output.
+	// Wait blocks until the subprocess exits and returns the full combined
+	// output and the exit error, if any.
 	Wait() (string, error)
 
 	// Pause sends SIGSTOP to the process group.
@@ -25,31 +30,134 @@ type Agent interface {
 
 	// Name returns "amp" or "claude".
 	Name() string
+
+	// SetRecorder registers a sink for the raw, unparsed output lines.
+	// Must be called before Start.
+	SetRecorder(r Recorder)
 }
 
+// LineKind classifies a line of agent output by where it came from.
+type LineKind int
+
+const (
+	LineText   LineKind = iota // assistant prose
+	LineTool                   // tool calls and echoed tool results
+	LineSystem                 // init, hooks, result summaries, stderr noise
+)
+
+// Line is a single human-readable line of agent output.
+type Line struct {
+	Text string
+	Kind LineKind
+
+	// Raw is Text without the timestamp it is displayed with.
+	Raw string
+
+	// Partial marks text assembled from streamed deltas, which the
+	// agent's final message repeats whole.
+	Partial bool
+
+	// CostUSD is the total cost reported by the agent, set only on the
+	// final result summary line.
+	CostUSD float64
+}
+
+// textLines wraps a raw line channel, tagging every line as assistant
+// text. Used for agents whose output is plain prose.
+func textLines(rawCh <-chan string) <-chan Line {
+	ch := make(chan Line, 256)
+	go func() {
+		defer close(ch)
+		for l := range rawCh {
+			ch <- Line{Text: l, Kind: LineText, Raw: l}
+		}
+	}()
+	return ch
+}
+
+// Recorder receives every raw line read from the agent before any parsing,
+// tagged with the stream it came from ("stdout" or "stderr").
+type Recorder interface {
+	RecordRaw(stream, line string)
+}
+
+// Options configures a new agent.
+type Options struct {
+	RalphDir   string
+	ProjectDir string
+	Model      string
+
+	// Settings holds agent-specific options from config.toml's
+	// [agentOptions] table (see config.AgentOptionsFor).
+	Settings map[string]any
+
+	// Guidance holds notes from the user to append to the prompt.
+	Guidance []string
+
+	// Story is the story the loop expects the agent to pick, if known.
+	// Real agents choose for themselves; the scripted agent plays it.
+	Story *prd.UserStory
+
+	// Task, when set, describes the story to work on and is appended to
+	// the prompt. Used for trackers the agent cannot read by itself.
+	Task string
+
+	// Prompt, when set, is sent instead of the prompt file (CLAUDE.md or
+	// prompt.md), e.g. for a one-off planning run.
+	Prompt []byte
+}
+
+// Names lists the agents accepted by New.
+var Names = []string{"amp", "claude", "scripted"}
+
 // New creates a new agent by name.
-func New(name, ralphDir, projectDir, model string) Agent {
+func New(name string, opts Options) Agent {
 	switch name {
 	case "claude":
 		return &ClaudeAgent{
 			ProcessManager: &ProcessManager{},
-			ralphDir:       ralphDir,
-			projectDir:     projectDir,
-			model:          model,
+			ralphDir:       opts.RalphDir,
+			projectDir:     opts.ProjectDir,
+			model:          opts.Model,
+			guidance:       opts.Guidance,
+			task:           opts.Task,
+			prompt:         opts.Prompt,
 		}
 	case "amp":
 		return &AmpAgent{
 			ProcessManager: &ProcessManager{},
-			ralphDir:       ralphDir,
-			projectDir:     projectDir,
-			model:          model,
+			ralphDir:       opts.RalphDir,
+			projectDir:     opts.ProjectDir,
+			model:          opts.Model,
+			guidance:       opts.Guidance,
+			task:           opts.Task,
+			prompt:         opts.Prompt,
+		}
+	case "scripted":
+		return &ScriptedAgent{
+			ralphDir:   opts.RalphDir,
+			projectDir: opts.ProjectDir,
+			fixture:    settingString(opts.Settings, "fixture"),
+			signals:    SignalsFromSettings(opts.Settings),
+			guidance:   opts.Guidance,
+			story:      opts.Story,
+			planning:   opts.Prompt != nil,
 		}
 	default:
 		return &ClaudeAgent{
 			ProcessManager: &ProcessManager{},
-			ralphDir:       ralphDir,
-			projectDir:     projectDir,
-			model:          model,
+			ralphDir:       opts.RalphDir,
+			projectDir:     opts.ProjectDir,
+			model:          opts.Model,
+			guidance:       opts.Guidance,
+			task:           opts.Task,
+			prompt:         opts.Prompt,
 		}
 	}
 }
+
+// settingString returns a string-valued setting, or "" if absent.
+func settingString(settings map[string]any, key string) string {
+	v, _ := settings[key].(string)
+	return v
+}
@@ -3,8 +3,6 @@ package agent
 import (
 	"bytes"
 	"context"
-	"fmt"
-	"os"
 	"os/exec"
 	"path/filepath"
 )
@@ -14,15 +12,17 @@ type AmpAgent struct {
 	ralphDir   string
 	projectDir string
 	model      string
+	guidance   []string
+	task       string
+	prompt     []byte
 }
 
 func (a *AmpAgent) Name() string { return "amp" }
 
-func (a *AmpAgent) Start(ctx context.Context) (<-chan string, error) {
-	promptPath := filepath.Join(a.ralphDir, "prompt.md")
-	promptContent, err := os.ReadFile(promptPath)
+func (a *AmpAgent) Start(ctx context.Context) (<-chan Line, error) {
+	promptContent, err := readPrompt(filepath.Join(a.ralphDir, "prompt.md"), a.prompt, a.task, a.guidance)
 	if err != nil {
-		return nil, fmt.Errorf("reading %s: %w", promptPath, err)
+		return nil, err
 	}
 
 	args := []string{"--dangerously-allow-all"}
@@ -32,5 +32,9 @@ func (a *AmpAgent) Start(ctx context.Context) (<-chan string, error) {
 	cmd := exec.CommandContext(ctx, "amp", args...)
 	cmd.Dir = a.projectDir
 
-	return a.start(cmd, bytes.NewReader(promptContent))
+	rawCh, err := a.start(cmd, bytes.NewReader(promptContent))
+	if err != nil {
+		return nil, err
+	}
+	return textLines(rawCh), nil
 }
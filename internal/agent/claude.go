@@ -1,9 +1,8 @@
 package agent
 
 import (
+	"bytes"
 	"context"
-	"fmt"
-	"os"
 	"os/exec"
 	"path/filepath"
 )
@@ -13,15 +12,17 @@ type ClaudeAgent struct {
 	ralphDir   string
 	projectDir string
 	model      string
+	guidance   []string
+	task       string
+	prompt     []byte
 }
 
 func (a *ClaudeAgent) Name() string { return "claude" }
 
-func (a *ClaudeAgent) Start(ctx context.Context) (<-chan string, error) {
-	claudeMDPath := filepath.Join(a.ralphDir, "CLAUDE.md")
-	f, err := os.Open(claudeMDPath)
+func (a *ClaudeAgent) Start(ctx context.Context) (<-chan Line, error) {
+	prompt, err := readPrompt(filepath.Join(a.ralphDir, "CLAUDE.md"), a.prompt, a.task, a.guidance)
 	if err != nil {
-		return nil, fmt.Errorf("opening %s: %w", claudeMDPath, err)
+		return nil, err
 	}
 
 	args := []string{
@@ -37,27 +38,10 @@ func (a *ClaudeAgent) Start(ctx context.Context) (<-chan string, error) {
 	cmd := exec.CommandContext(ctx, "claude", args...)
 	cmd.Dir = a.projectDir
 
-	rawCh, err := a.start(cmd, f)
+	rawCh, err := a.start(cmd, bytes.NewReader(prompt))
 	if err != nil {
-		f.Close()
 		return nil, err
 	}
 
-	// Parse stream-json into human-readable lines with stateful delta accumulation
-	parsedCh := make(chan string, 256)
-	go func() {
-		defer close(parsedCh)
-		parser := newStreamParser()
-		for line := range rawCh {
-			for _, parsed := range parser.parseLine(line) {
-				parsedCh <- parsed
-			}
-		}
-		// Flush any remaining partial text
-		for _, flushed := range parser.flush() {
-			parsedCh <- flushed
-		}
-	}()
-
-	return parsedCh, nil
+	return parseStream(rawCh), nil
 }
@@ -16,8 +16,10 @@ import (
 type ProcessManager struct {
 	cmd       *exec.Cmd
 	allOutput strings.Builder
+	recorder  Recorder
 	paused    atomic.Bool
 	done      atomic.Bool
+	exitErr   error // from cmd.Wait, guarded by mu
 	mu        sync.Mutex
 }
 
@@ -35,6 +37,7 @@ func (pm *ProcessManager) start(cmd *exec.Cmd, stdin io.Reader) (<-chan string,
 	pm.allOutput.Reset()
 	pm.paused.Store(false)
 	pm.done.Store(false)
+	pm.exitErr = nil
 
 	// Process group for pause/resume/kill of all children
 	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
@@ -62,7 +65,8 @@ func (pm *ProcessManager) start(cmd *exec.Cmd, stdin io.Reader) (<-chan string,
 	var wg sync.WaitGroup
 	wg.Add(2)
 
-	readPipe := func(pipe io.ReadCloser) {
+	recorder := pm.recorder
+	readPipe := func(stream string, pipe io.ReadCloser) {
 		defer wg.Done()
 		scanner := bufio.NewScanner(pipe)
 		scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
@@ -72,17 +76,23 @@ func (pm *ProcessManager) start(cmd *exec.Cmd, stdin io.Reader) (<-chan string,
 			pm.allOutput.WriteString(line)
 			pm.allOutput.WriteByte('\n')
 			pm.mu.Unlock()
+			if recorder != nil {
+				recorder.RecordRaw(stream, line)
+			}
 			ch <- line
 		}
 	}
 
-	go readPipe(stdoutPipe)
-	go readPipe(stderrPipe)
+	go readPipe("stdout", stdoutPipe)
+	go readPipe("stderr", stderrPipe)
 
 	// Close channel when both pipes are drained and process exits
 	go func() {
 		wg.Wait()
-		cmd.Wait()
+		err := cmd.Wait()
+		pm.mu.Lock()
+		pm.exitErr = err
+		pm.mu.Unlock()
 		pm.done.Store(true)
 		close(ch)
 	}()
@@ -90,13 +100,19 @@ func (pm *ProcessManager) start(cmd *exec.Cmd, stdin io.Reader) (<-chan string,
 	return ch, nil
 }
 
+func (pm *ProcessManager) SetRecorder(r Recorder) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.recorder = r
+}
+
 func (pm *ProcessManager) Wait() (string, error) {
 	for !pm.done.Load() {
 		time.Sleep(50 * time.Millisecond)
 	}
 	pm.mu.Lock()
 	defer pm.mu.Unlock()
-	return pm.allOutput.String(), nil
+	return pm.allOutput.String(), pm.exitErr
 }
 
 func (pm *ProcessManager) Pause() error {
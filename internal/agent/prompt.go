@@ -0,0 +1,55 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// readPrompt reads the agent's instruction file, or uses override if set,
+// and appends the task and any pending human guidance notes.
+func readPrompt(path string, override []byte, task string, guidance []string) ([]byte, error) {
+	content := override
+	if content == nil {
+		var err error
+		if content, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("reading %s: %w", path, err)
+		}
+	}
+	return withGuidance(withTask(content, task), guidance), nil
+}
+
+// withTask appends a "Current story" section to a prompt. Returns the
+// prompt unchanged when task is empty.
+func withTask(prompt []byte, task string) []byte {
+	if task == "" {
+		return prompt
+	}
+	var b strings.Builder
+	b.Write(prompt)
+	if len(prompt) > 0 && prompt[len(prompt)-1] != '\n' {
+		b.WriteByte('\n')
+	}
+	b.WriteString("\n## Current story\n\n")
+	b.WriteString(strings.TrimRight(task, "\n") + "\n")
+	return []byte(b.String())
+}
+
+// withGuidance appends a "Human guidance" section to a prompt. Returns the
+// prompt unchanged when there are no notes.
+func withGuidance(prompt []byte, notes []string) []byte {
+	if len(notes) == 0 {
+		return prompt
+	}
+	var b strings.Builder
+	b.Write(prompt)
+	if len(prompt) > 0 && prompt[len(prompt)-1] != '\n' {
+		b.WriteByte('\n')
+	}
+	b.WriteString("\n## Human guidance\n\n")
+	b.WriteString("The human supervising this loop left these notes. They take precedence over the instructions above.\n\n")
+	for _, n := range notes {
+		b.WriteString("- " + strings.ReplaceAll(n, "\n", "\n  ") + "\n")
+	}
+	return []byte(b.String())
+}
@@ -0,0 +1,162 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ReplayLine is a single raw line from a recorded agent stream, with its
+// offset from the start of the iteration.
+type ReplayLine struct {
+	Offset time.Duration
+	Line   string
+}
+
+// ReplayAgent feeds a recorded raw stream back through the same parsing
+// path the original agent used, honouring the recorded timing.
+type ReplayAgent struct {
+	player
+	agentName string
+	lines     []ReplayLine
+	speed     float64
+}
+
+// NewReplay creates an agent that replays lines recorded from agentName.
+// speed scales the recorded delays (2 = twice as fast); 0 replays instantly.
+func NewReplay(agentName string, lines []ReplayLine, speed float64) *ReplayAgent {
+	return &ReplayAgent{
+		agentName: agentName,
+		lines:     lines,
+		speed:     speed,
+	}
+}
+
+func (a *ReplayAgent) Name() string { return a.agentName }
+
+// SetRecorder is a no-op: replayed output is never re-recorded.
+func (a *ReplayAgent) SetRecorder(Recorder) {}
+
+func (a *ReplayAgent) Start(ctx context.Context) (<-chan Line, error) {
+	rawCh := make(chan string, 256)
+	a.reset()
+
+	go func() {
+		defer a.finish()
+		defer close(rawCh)
+		var prev time.Duration
+		for _, l := range a.lines {
+			delay := l.Offset - prev
+			prev = l.Offset
+			if a.speed > 0 && delay > 0 {
+				if !a.sleep(ctx, time.Duration(float64(delay)/a.speed)) {
+					return
+				}
+			} else if a.stopped(ctx) {
+				return
+			}
+			a.record(l.Line)
+			rawCh <- l.Line
+		}
+	}()
+
+	if a.agentName == "claude" {
+		return parseStream(rawCh), nil
+	}
+	return textLines(rawCh), nil
+}
+
+// player provides pause/resume/kill semantics for agents that emit
+// output from an in-process goroutine instead of a subprocess.
+type player struct {
+	output strings.Builder
+	paused atomic.Bool
+	done   atomic.Bool
+	stop   chan struct{}
+	once   sync.Once
+	mu     sync.Mutex
+}
+
+func (p *player) reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.output.Reset()
+	p.paused.Store(false)
+	p.done.Store(false)
+	p.stop = make(chan struct{})
+	p.once = sync.Once{}
+}
+
+func (p *player) record(line string) {
+	p.mu.Lock()
+	p.output.WriteString(line)
+	p.output.WriteByte('\n')
+	p.mu.Unlock()
+}
+
+func (p *player) finish() {
+	p.done.Store(true)
+}
+
+// sleep waits for d of unpaused time. Returns false if the player was
+// killed or the context cancelled in the meantime.
+func (p *player) sleep(ctx context.Context, d time.Duration) bool {
+	const step = 50 * time.Millisecond
+	for d > 0 || p.paused.Load() {
+		wait := step
+		if !p.paused.Load() && d < step {
+			wait = d
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-p.stop:
+			return false
+		case <-time.After(wait):
+		}
+		if !p.paused.Load() {
+			d -= wait
+		}
+	}
+	return true
+}
+
+// stopped reports whether the player was killed, blocking while paused.
+func (p *player) stopped(ctx context.Context) bool {
+	return !p.sleep(ctx, 0)
+}
+
+func (p *player) Wait() (string, error) {
+	for !p.done.Load() {
+		time.Sleep(50 * time.Millisecond)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.output.String(), nil
+}
+
+func (p *player) Pause() error {
+	p.paused.Store(true)
+	return nil
+}
+
+func (p *player) Resume() error {
+	p.paused.Store(false)
+	return nil
+}
+
+func (p *player) Kill() error {
+	p.paused.Store(false)
+	p.once.Do(func() {
+		if p.stop != nil {
+			close(p.stop)
+		}
+	})
+	return nil
+}
+
+func (p *player) IsPaused() bool {
+	return p.paused.Load()
+}
@@ -0,0 +1,212 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/zhrkvl/ralph-go/internal/prd"
+)
+
+// ScriptedAgent plays back a fixture file instead of calling a real agent,
+// so the loop can be exercised offline and deterministically.
+//
+// Like a real agent it reads the PRD, picks the current story and runs the
+// fixture steps for that story (or the default steps).
+type ScriptedAgent struct {
+	player
+	ralphDir   string
+	projectDir string
+	fixture    string
+	signals    Signals
+	guidance   []string
+	story      *prd.UserStory // picked by the loop; nil reads the PRD
+	planning   bool           // given a prompt instead of the prompt file: run "plan"
+	recorder   Recorder
+}
+
+// Fixture is the on-disk format of a scripted agent run.
+//
+//	{
+//	  "plan": [{"output": "<prd>{\"name\": \"Demo\", ...}</prd>"}],
+//	  "stories": {"US-002": [{"output": "cannot do this"}]},
+//	  "default": [
+//	    {"delayMs": 200, "output": "Working on {{story}}"},
+//	    {"write": "notes/{{story}}.txt", "content": "done\n"},
+//	    {"pass": "{{story}}"},
+//	    {"complete": true}
+//	  ]
+//	}
+//
+// "plan" runs instead of the story steps when the agent is started with a
+// custom prompt, as `ralph plan` does.
+type Fixture struct {
+	Plan    []FixtureStep            `json:"plan,omitempty"`
+	Stories map[string][]FixtureStep `json:"stories,omitempty"`
+	Default []FixtureStep            `json:"default"`
+}
+
+// FixtureStep is one timed action. Fields are applied in declaration order;
+// {{story}} and {{title}} are replaced with the current story's ID and title.
+type FixtureStep struct {
+	DelayMs  int    `json:"delayMs,omitempty"`
+	Output   string `json:"output,omitempty"`   // line(s) to emit on stdout
+	Stderr   string `json:"stderr,omitempty"`   // line(s) to emit on stderr
+	Write    string `json:"write,omitempty"`    // file to write, relative to the project dir
+	Content  string `json:"content,omitempty"`  // content for Write
+	Append   bool   `json:"append,omitempty"`   // append to Write instead of replacing it
+	Pass     string `json:"pass,omitempty"`     // story ID to mark passes=true in the PRD
+	Complete bool   `json:"complete,omitempty"` // emit the completion signal if every story passes, else say why not on stderr
+}
+
+func (a *ScriptedAgent) Name() string { return "scripted" }
+
+func (a *ScriptedAgent) SetRecorder(r Recorder) { a.recorder = r }
+
+func (a *ScriptedAgent) Start(ctx context.Context) (<-chan Line, error) {
+	fixturePath := a.fixture
+	if fixturePath == "" {
+		fixturePath = filepath.Join(a.ralphDir, "fixture.json")
+	} else if !filepath.IsAbs(fixturePath) {
+		fixturePath = filepath.Join(a.projectDir, fixturePath)
+	}
+	fx, err := LoadFixture(fixturePath)
+	if err != nil {
+		return nil, err
+	}
+
+	prdPath := prd.Find(a.ralphDir)
+	if prdPath == "" {
+		prdPath = filepath.Join(a.ralphDir, "prd.json")
+	}
+	steps := fx.Plan
+	story := a.story
+	if !a.planning {
+		if story == nil {
+			p, err := prd.Load(prdPath)
+			if err != nil {
+				return nil, err
+			}
+			story = p.CurrentStory()
+		}
+		steps = fx.Default
+	}
+	replacer := strings.NewReplacer("{{story}}", "", "{{title}}", "")
+	if story != nil {
+		if s, ok := fx.Stories[story.ID]; ok {
+			steps = s
+		}
+		replacer = strings.NewReplacer("{{story}}", story.ID, "{{title}}", story.Title)
+	}
+
+	ch := make(chan Line, 256)
+	a.reset()
+
+	emit := func(stream, text string) {
+		kind := LineText
+		if stream == "stderr" {
+			kind = LineSystem
+		}
+		for _, line := range strings.Split(replacer.Replace(text), "\n") {
+			if a.recorder != nil {
+				a.recorder.RecordRaw(stream, line)
+			}
+			a.record(line)
+			for _, l := range stamp([]Line{{Text: line, Kind: kind}}) {
+				ch <- l
+			}
+		}
+	}
+
+	go func() {
+		defer a.finish()
+		defer close(ch)
+		for _, note := range a.guidance {
+			emit("stderr", "[guidance] "+note)
+		}
+		for _, step := range steps {
+			if !a.sleep(ctx, time.Duration(step.DelayMs)*time.Millisecond) {
+				return
+			}
+			if step.Output != "" {
+				emit("stdout", step.Output)
+			}
+			if step.Stderr != "" {
+				emit("stderr", step.Stderr)
+			}
+			if step.Write != "" {
+				path := filepath.Join(a.projectDir, replacer.Replace(step.Write))
+				if err := writeFixtureFile(path, replacer.Replace(step.Content), step.Append); err != nil {
+					emit("stderr", err.Error())
+				}
+			}
+			if step.Pass != "" {
+				if err := passStory(prdPath, replacer.Replace(step.Pass)); err != nil {
+					emit("stderr", err.Error())
+				}
+			}
+			if step.Complete {
+				p, err := prd.Load(prdPath)
+				switch {
+				case err != nil:
+					emit("stderr", "fixture: not completing: "+err.Error())
+				case p.RemainingCount() > 0:
+					emit("stderr", fmt.Sprintf("fixture: not completing, %d stories still open", p.RemainingCount()))
+				default:
+					emit("stdout", a.signals.Complete)
+				}
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// LoadFixture reads and parses a scripted agent fixture file.
+func LoadFixture(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading fixture: %w", err)
+	}
+	var fx Fixture
+	if err := json.Unmarshal(data, &fx); err != nil {
+		return nil, fmt.Errorf("parsing fixture %s: %w", path, err)
+	}
+	return &fx, nil
+}
+
+func writeFixtureFile(path, content string, appendMode bool) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if appendMode {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	f, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.WriteString(content)
+	return err
+}
+
+// passStory flips passes=true for the given story, as a real agent would.
+func passStory(prdPath, id string) error {
+	p, err := prd.Load(prdPath)
+	if err != nil {
+		return err
+	}
+	for i := range p.UserStories {
+		if p.UserStories[i].ID == id {
+			p.UserStories[i].Passes = true
+			return p.Save(prdPath)
+		}
+	}
+	return fmt.Errorf("fixture: story %s not found in %s", id, filepath.Base(prdPath))
+}
@@ -0,0 +1,100 @@
+package agent
+
+import (
+	"regexp"
+	"strings"
+)
+
+// SignalKind identifies a control signal emitted by the agent.
+type SignalKind int
+
+const (
+	SignalComplete  SignalKind = iota // all stories done, stop the loop
+	SignalBlocked                     // needs a human, pause the loop
+	SignalStoryDone                   // a single story finished
+	SignalAbort                       // stop the session as failed
+)
+
+// Signal is a control signal found in the agent's output.
+type Signal struct {
+	Kind    SignalKind
+	StoryID string // set for SignalStoryDone
+	Reason  string // any text following the marker on the same line
+}
+
+// Signals holds the markers an agent uses to talk to the loop. An empty
+// marker disables that signal. StoryDone must contain "{id}", which
+// matches the story ID.
+type Signals struct {
+	Complete  string
+	Blocked   string
+	StoryDone string
+	Abort     string
+
+	storyDone *regexp.Regexp
+}
+
+// DefaultSignals returns the built-in markers.
+func DefaultSignals() Signals {
+	return Signals{
+		Complete:  "<promise>COMPLETE</promise>",
+		Blocked:   "<promise>BLOCKED</promise>",
+		StoryDone: "<promise>STORY_DONE:{id}</promise>",
+		Abort:     "<promise>ABORT</promise>",
+	}.compile()
+}
+
+// SignalsFromSettings returns the default markers overridden by the
+// completionSignal, blockedSignal, storyDoneSignal and abortSignal
+// agent options.
+func SignalsFromSettings(settings map[string]any) Signals {
+	s := DefaultSignals()
+	override := func(dst *string, key string) {
+		if v, ok := settings[key].(string); ok {
+			*dst = v
+		}
+	}
+	override(&s.Complete, "completionSignal")
+	override(&s.Blocked, "blockedSignal")
+	override(&s.StoryDone, "storyDoneSignal")
+	override(&s.Abort, "abortSignal")
+	return s.compile()
+}
+
+func (s Signals) compile() Signals {
+	s.storyDone = nil
+	if before, after, ok := strings.Cut(s.StoryDone, "{id}"); ok {
+		s.storyDone = regexp.MustCompile(regexp.QuoteMeta(before) + `([A-Za-z0-9._#/-]+)` + regexp.QuoteMeta(after))
+	}
+	return s
+}
+
+// Match returns the signals present in a line. Only assistant text is
+// considered, so markers echoed back in tool calls or tool results (e.g.
+// the agent reading its own prompt) never trigger anything.
+func (s Signals) Match(line Line) []Signal {
+	if line.Kind != LineText {
+		return nil
+	}
+	text := line.Text
+	var found []Signal
+	if s.Complete != "" && strings.Contains(text, s.Complete) {
+		found = append(found, Signal{Kind: SignalComplete})
+	}
+	if s.Blocked != "" {
+		if _, after, ok := strings.Cut(text, s.Blocked); ok {
+			found = append(found, Signal{Kind: SignalBlocked, Reason: strings.TrimSpace(after)})
+		}
+	}
+	if s.Abort != "" {
+		if _, after, ok := strings.Cut(text, s.Abort); ok {
+			found = append(found, Signal{Kind: SignalAbort, Reason: strings.TrimSpace(after)})
+		}
+	}
+	if s.storyDone != nil {
+		for _, m := range s.storyDone.FindAllStringSubmatch(text, -1) {
+			found = append(found, Signal{Kind: SignalStoryDone, StoryID: m[1]})
+		}
+	}
+	return found
+}
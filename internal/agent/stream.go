@@ -16,19 +16,51 @@ func newStreamParser() *streamParser {
 	return &streamParser{}
 }
 
+// parseStream converts raw stream-json lines into human-readable lines with
+// stateful delta accumulation. The returned channel closes after rawCh does.
+func parseStream(rawCh <-chan string) <-chan Line {
+	parsedCh := make(chan Line, 256)
+	go func() {
+		defer close(parsedCh)
+		parser := newStreamParser()
+		for line := range rawCh {
+			for _, parsed := range parser.parseLine(line) {
+				parsedCh <- parsed
+			}
+		}
+		// Flush any remaining partial text
+		for _, flushed := range parser.flush() {
+			parsedCh <- flushed
+		}
+	}()
+	return parsedCh
+}
+
 // stamp prepends a local timestamp to each non-empty line.
-func stamp(lines []string) []string {
+func stamp(lines []Line) []Line {
 	if len(lines) == 0 {
 		return nil
 	}
 	ts := time.Now().Format("15:04:05")
-	out := make([]string, 0, len(lines))
+	out := make([]Line, 0, len(lines))
 	for _, l := range lines {
-		if l == "" {
-			out = append(out, "")
-		} else {
-			out = append(out, ts+" "+l)
+		l.Raw = l.Text
+		if l.Text != "" {
+			l.Text = ts + " " + l.Text
 		}
+		out = append(out, l)
+	}
+	return out
+}
+
+// kinded tags plain lines with a single kind.
+func kinded(kind LineKind, lines []string) []Line {
+	if len(lines) == 0 {
+		return nil
+	}
+	out := make([]Line, len(lines))
+	for i, l := range lines {
+		out[i] = Line{Text: l, Kind: kind}
 	}
 	return out
 }
@@ -41,7 +73,7 @@ func stamp(lines []string) []string {
 //   {"type":"assistant","message":{"content":[{"type":"text","text":"..."},{"type":"tool_use",...}]}}
 //   {"type":"stream_event","event":{"type":"content_block_delta","delta":{"type":"text_delta","text":"..."}}}
 //   {"type":"result",...}
-func (sp *streamParser) parseLine(line string) []string {
+func (sp *streamParser) parseLine(line string) []Line {
 	line = strings.TrimSpace(line)
 	if line == "" {
 		return nil
@@ -50,7 +82,7 @@ func (sp *streamParser) parseLine(line string) []string {
 	var event map[string]any
 	if err := json.Unmarshal([]byte(line), &event); err != nil {
 		// Not JSON — return as-is (might be plain text from stderr)
-		return stamp([]string{line})
+		return stamp(kinded(LineSystem, []string{line}))
 	}
 
 	typ, _ := event["type"].(string)
@@ -59,7 +91,7 @@ func (sp *streamParser) parseLine(line string) []string {
 	case "stream_event":
 		return stamp(sp.parseStreamEvent(event))
 	case "system":
-		return stamp(sp.flushAndParse(parseSystemEvent(event)))
+		return stamp(sp.flushAndParse(kinded(LineSystem, parseSystemEvent(event))))
 	case "assistant":
 		return stamp(sp.flushAndParse(parseAssistantEvent(event)))
 	case "result":
@@ -70,23 +102,23 @@ func (sp *streamParser) parseLine(line string) []string {
 }
 
 // flush emits any accumulated text as a stamped line (even if no trailing newline).
-func (sp *streamParser) flush() []string {
+func (sp *streamParser) flush() []Line {
 	if sp.textBuf.Len() == 0 {
 		return nil
 	}
 	line := sp.textBuf.String()
 	sp.textBuf.Reset()
-	return stamp([]string{line})
+	return stamp([]Line{{Text: line, Kind: LineText, Partial: true}})
 }
 
 // flushAndParse flushes the text buffer before returning other parsed lines.
-func (sp *streamParser) flushAndParse(lines []string) []string {
+func (sp *streamParser) flushAndParse(lines []Line) []Line {
 	flushed := sp.flush()
 	return append(flushed, lines...)
 }
 
 // parseStreamEvent handles token-by-token text_delta events.
-func (sp *streamParser) parseStreamEvent(event map[string]any) []string {
+func (sp *streamParser) parseStreamEvent(event map[string]any) []Line {
 	ev, ok := event["event"].(map[string]any)
 	if !ok {
 		return nil
@@ -109,10 +141,10 @@ func (sp *streamParser) parseStreamEvent(event map[string]any) []string {
 	}
 
 	// Accumulate text. Emit complete lines (split on newline).
-	var lines []string
+	var lines []Line
 	for _, ch := range text {
 		if ch == '\n' {
-			lines = append(lines, sp.textBuf.String())
+			lines = append(lines, Line{Text: sp.textBuf.String(), Kind: LineText, Partial: true})
 			sp.textBuf.Reset()
 		} else {
 			sp.textBuf.WriteRune(ch)
@@ -139,7 +171,7 @@ func parseSystemEvent(event map[string]any) []string {
 	return nil
 }
 
-func parseAssistantEvent(event map[string]any) []string {
+func parseAssistantEvent(event map[string]any) []Line {
 	msg, ok := event["message"].(map[string]any)
 	if !ok {
 		return nil
@@ -150,7 +182,7 @@ func parseAssistantEvent(event map[string]any) []string {
 		return nil
 	}
 
-	var lines []string
+	var lines []Line
 	for _, item := range content {
 		block, ok := item.(map[string]any)
 		if !ok {
@@ -163,14 +195,14 @@ func parseAssistantEvent(event map[string]any) []string {
 			text, _ := block["text"].(string)
 			if text != "" {
 				for _, l := range strings.Split(text, "\n") {
-					lines = append(lines, l)
+					lines = append(lines, Line{Text: l, Kind: LineText})
 				}
 			}
 
 		case "tool_use":
 			name, _ := block["name"].(string)
 			input, _ := block["input"].(map[string]any)
-			lines = append(lines, formatToolUse(name, input))
+			lines = append(lines, Line{Text: formatToolUse(name, input), Kind: LineTool})
 
 		case "tool_result":
 			content := extractToolResultContent(block)
@@ -179,19 +211,19 @@ func parseAssistantEvent(event map[string]any) []string {
 				if len(first) > 120 {
 					first = first[:117] + "..."
 				}
-				lines = append(lines, fmt.Sprintf("  → %s", first))
+				lines = append(lines, Line{Text: fmt.Sprintf("  → %s", first), Kind: LineTool})
 			}
 
 		case "server_tool_use":
 			name, _ := block["name"].(string)
-			lines = append(lines, fmt.Sprintf("[%s]", name))
+			lines = append(lines, Line{Text: fmt.Sprintf("[%s]", name), Kind: LineTool})
 		}
 	}
 
 	return lines
 }
 
-func parseResultEvent(event map[string]any) []string {
+func parseResultEvent(event map[string]any) []Line {
 	subtype, _ := event["subtype"].(string)
 	durationMs, _ := event["duration_ms"].(float64)
 	numTurns, _ := event["num_turns"].(float64)
@@ -218,7 +250,7 @@ func parseResultEvent(event map[string]any) []string {
 		line += fmt.Sprintf(" | $%.4f", cost)
 	}
 
-	return []string{line}
+	return []Line{{Text: line, Kind: LineSystem, CostUSD: cost}}
 }
 
 func formatToolUse(name string, input map[string]any) string {
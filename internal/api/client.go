@@ -0,0 +1,186 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zhrkvl/ralph-go/internal/prd"
+)
+
+// Client talks to the API of a running session.
+type Client struct {
+	base  string
+	token string
+	http  *http.Client
+}
+
+// NewClient returns a client for addr, in the form Listen takes, of the
+// session running in the project dir. On TCP it authenticates with the
+// token that session wrote there.
+func NewClient(addr, dir string) *Client {
+	c := &Client{base: "http://" + addr, http: &http.Client{}}
+	if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+		c.base = "http://ralph"
+		c.http.Transport = &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", path)
+			},
+		}
+	} else if data, err := os.ReadFile(TokenPath(dir)); err == nil {
+		c.token = strings.TrimSpace(string(data))
+	}
+	return c
+}
+
+// Status fetches GET /status.
+func (c *Client) Status(ctx context.Context) (*Status, error) {
+	var st Status
+	if err := c.do(ctx, http.MethodGet, "/status", nil, &st); err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+// Stories fetches GET /stories.
+func (c *Client) Stories(ctx context.Context) (*prd.PRD, error) {
+	var p prd.PRD
+	if err := c.do(ctx, http.MethodGet, "/stories", nil, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Control sends a control request and returns the server's message. A
+// request that does not apply now fails with an error wrapping
+// ErrConflict.
+func (c *Client) Control(ctx context.Context, a Action, note string) (string, error) {
+	in := map[string]string{}
+	if a == Note {
+		in["note"] = note
+	}
+	body, err := json.Marshal(in)
+	if err != nil {
+		return "", err
+	}
+	var out struct {
+		Message string `json:"message"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/"+string(a), bytes.NewReader(body), &out); err != nil {
+		return "", err
+	}
+	return out.Message, nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body io.Reader, out any) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := c.newRequest(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		msg := strings.TrimSpace(string(data))
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error != "" {
+			msg = apiErr.Error
+		}
+		if resp.StatusCode == http.StatusConflict {
+			// The server's message already starts with ErrConflict's text.
+			return fmt.Errorf("%w%s", ErrConflict, strings.TrimPrefix(msg, ErrConflict.Error()))
+		}
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, msg)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("decoding %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.base+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	return req, nil
+}
+
+// Event is one event from GET /events.
+type Event struct {
+	ID   int
+	Name string
+	Data json.RawMessage
+}
+
+// Events streams GET /events to fn until ctx ends or the server closes
+// the stream. lastID asks for the buffered events after it first; -1
+// asks for none.
+func (c *Client) Events(ctx context.Context, lastID int, fn func(Event)) error {
+	req, err := c.newRequest(ctx, http.MethodGet, "/events", nil)
+	if err != nil {
+		return err
+	}
+	if lastID >= 0 {
+		req.Header.Set("Last-Event-ID", strconv.Itoa(lastID))
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET /events: %s", resp.Status)
+	}
+
+	sc := bufio.NewScanner(resp.Body)
+	sc.Buffer(make([]byte, 64<<10), 4<<20)
+	var ev Event
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case line == "":
+			if ev.Name != "" {
+				fn(ev)
+			}
+			ev = Event{}
+		case strings.HasPrefix(line, "id: "):
+			ev.ID, _ = strconv.Atoi(line[4:])
+		case strings.HasPrefix(line, "event: "):
+			ev.Name = line[7:]
+		case strings.HasPrefix(line, "data: "):
+			ev.Data = json.RawMessage(line[6:])
+		}
+	}
+	if err := sc.Err(); err != nil && !errors.Is(ctx.Err(), context.Canceled) {
+		return err
+	}
+	return nil
+}
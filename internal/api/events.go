@@ -0,0 +1,75 @@
+package api
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// backlogSize is how many events are kept for clients that reconnect with
+// Last-Event-ID.
+const backlogSize = 500
+
+// event is one server-sent event, already encoded.
+type event struct {
+	id   int
+	name string
+	data []byte
+}
+
+// hub fans events out to the /events clients. A client that cannot keep
+// up misses events rather than slowing down the loop.
+type hub struct {
+	mu      sync.Mutex
+	nextID  int
+	backlog []event
+	clients map[chan event]struct{}
+}
+
+func newHub() *hub {
+	return &hub{nextID: 1, clients: map[chan event]struct{}{}}
+}
+
+func (h *hub) publish(name string, data any) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ev := event{id: h.nextID, name: name, data: b}
+	h.nextID++
+	h.backlog = append(h.backlog, ev)
+	if len(h.backlog) > backlogSize {
+		h.backlog = h.backlog[len(h.backlog)-backlogSize:]
+	}
+	for c := range h.clients {
+		select {
+		case c <- ev:
+		default:
+		}
+	}
+}
+
+// subscribe registers a client and returns the buffered events after
+// lastID, if lastID >= 0, followed by everything published from now on.
+func (h *hub) subscribe(lastID int) (chan event, []event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	c := make(chan event, 256)
+	h.clients[c] = struct{}{}
+	var missed []event
+	if lastID >= 0 {
+		for _, ev := range h.backlog {
+			if ev.id > lastID {
+				missed = append(missed, ev)
+			}
+		}
+	}
+	return c, missed
+}
+
+func (h *hub) unsubscribe(c chan event) {
+	h.mu.Lock()
+	delete(h.clients, c)
+	h.mu.Unlock()
+}
@@ -0,0 +1,425 @@
+// Package api serves a running session over HTTP, on a loopback address
+// or a Unix socket, so editors, scripts and other ralph commands can watch
+// and steer the loop.
+//
+//	GET  /status   session, iteration, current story and loop state
+//	GET  /stories  the PRD as last loaded
+//	GET  /events   server-sent events: agent output and loop events
+//	POST /pause    pause the running agent
+//	POST /resume   resume a paused agent, or continue a held loop
+//	POST /skip     kill the running agent and go on with the next iteration
+//	POST /stop     interrupt the session and exit
+//	POST /note     queue guidance for the agent: {"note": "..."}
+//
+// Responses are JSON. Actions that do not apply in the current state,
+// such as pausing when no agent runs, answer 409 Conflict.
+//
+// The API is only for processes of the user running ralph. A Unix socket
+// is created accessible to its owner only. On a TCP port, which any local
+// user can reach, requests must carry the session's bearer token, which is
+// written to .ralph-tui/api.token readable by the owner only. Requests
+// from browsers are refused: those with an Origin header, those for a Host
+// other than a loopback one (DNS rebinding), and POSTs that are not
+// application/json, which a page cannot send without asking first.
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/zhrkvl/ralph-go/internal/session"
+)
+
+// Action is a control request.
+type Action string
+
+const (
+	Pause  Action = "pause"
+	Resume Action = "resume"
+	Skip   Action = "skip"
+	Stop   Action = "stop"
+	Note   Action = "note"
+)
+
+// ErrConflict marks a control request that does not apply in the current
+// state of the loop.
+var ErrConflict = errors.New("not possible now")
+
+// Status is the body of GET /status.
+type Status struct {
+	Session         *session.Session `json:"session"`
+	State           string           `json:"state"` // running, paused, waiting, blocked, reviewing, idle, or the session's final status
+	Iteration       int              `json:"iteration"`
+	MaxIterations   int              `json:"maxIterations"`
+	CurrentStory    *StoryRef        `json:"currentStory,omitempty"`
+	BlockedReason   string           `json:"blockedReason,omitempty"`
+	StepMode        bool             `json:"stepMode"`
+	PendingGuidance []string         `json:"pendingGuidance,omitempty"`
+}
+
+// StoryRef names a story.
+type StoryRef struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+// Output is the data of an "output" event: one line of agent output.
+type Output struct {
+	Iteration int    `json:"iteration"`
+	Text      string `json:"text"`
+}
+
+// Controller gives the server access to the loop. Status and Stories must
+// return values that share no memory with state the loop goes on to
+// change, since they are encoded on the server's goroutines.
+type Controller interface {
+	Status() (any, error)
+	Stories() (any, error)
+	Control(a Action, note string) (string, error)
+}
+
+// Server is the HTTP API of one session.
+type Server struct {
+	addr      string
+	socket    string // path to remove on Close, for unix: addresses
+	token     string // bearer token required on TCP; "" on a socket
+	tokenPath string // file the token is written to, removed on Close
+	ln        net.Listener
+	srv       *http.Server
+	events    *hub
+	ctx       context.Context // ends open event streams on Close
+	cancel    context.CancelFunc
+
+	mu  sync.Mutex
+	ctl Controller
+}
+
+// Listen opens addr: "unix:<path>" for a Unix socket, a relative path
+// being taken from dir, or host:port on a loopback address. Requests are
+// answered once Serve is called.
+func Listen(addr, dir string) (*Server, error) {
+	s := &Server{events: newHub()}
+	if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		if err := removeStaleSocket(path); err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, err
+		}
+		// Created with owner-only permissions, so no one else can connect
+		// in the moment before a chmod.
+		old := syscall.Umask(0o077)
+		ln, err := net.Listen("unix", path)
+		syscall.Umask(old)
+		if err != nil {
+			return nil, err
+		}
+		s.ln, s.socket, s.addr = ln, path, "unix:"+path
+	} else {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, fmt.Errorf("listen address %q: %w", addr, err)
+		}
+		if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			return nil, fmt.Errorf("listen address %q is not a loopback address; use 127.0.0.1, localhost or unix:<path>", addr)
+		}
+		if err := s.writeToken(dir); err != nil {
+			return nil, err
+		}
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			os.Remove(s.tokenPath)
+			return nil, err
+		}
+		s.ln, s.addr = ln, ln.Addr().String()
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status", s.handleStatus)
+	mux.HandleFunc("GET /stories", s.handleStories)
+	mux.HandleFunc("GET /events", s.handleEvents)
+	for _, a := range []Action{Pause, Resume, Skip, Stop, Note} {
+		mux.HandleFunc("POST /"+string(a), s.handleControl(a))
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.srv = &http.Server{
+		Handler:           s.guard(mux),
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return s.ctx },
+	}
+	go s.srv.Serve(s.ln)
+	return s, nil
+}
+
+// TokenPath returns the file the bearer token of the API served for the
+// project in dir is kept in.
+func TokenPath(dir string) string {
+	return filepath.Join(dir, ".ralph-tui", "api.token")
+}
+
+// writeToken makes up the session's token and writes it where clients of
+// the same user can read it.
+func (s *Server) writeToken(dir string) error {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	s.token, s.tokenPath = hex.EncodeToString(b), TokenPath(dir)
+	if err := os.MkdirAll(filepath.Dir(s.tokenPath), 0o755); err != nil {
+		return err
+	}
+	// Replaced rather than rewritten, so a file another user made
+	// readable does not keep its mode.
+	os.Remove(s.tokenPath)
+	f, err := os.OpenFile(s.tokenPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("writing the API token: %w", err)
+	}
+	_, err = f.WriteString(s.token + "\n")
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// guard refuses requests that may come from a browser or, on TCP, lack
+// the token.
+func (s *Server) guard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Origin") != "" {
+			writeError(w, http.StatusForbidden, errors.New("requests from web pages are not accepted"))
+			return
+		}
+		if s.socket == "" {
+			host, _, err := net.SplitHostPort(r.Host)
+			if err != nil {
+				host = r.Host
+			}
+			if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+				writeError(w, http.StatusForbidden, fmt.Errorf("host %q is not a loopback address", r.Host))
+				return
+			}
+			got, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(got), []byte(s.token)) != 1 {
+				writeError(w, http.StatusUnauthorized, fmt.Errorf("missing or wrong bearer token; it is in %s", s.tokenPath))
+				return
+			}
+		}
+		if r.Method == http.MethodPost {
+			if ct, _, _ := strings.Cut(r.Header.Get("Content-Type"), ";"); strings.TrimSpace(ct) != "application/json" {
+				writeError(w, http.StatusUnsupportedMediaType, errors.New("POST requests must be application/json"))
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// removeStaleSocket removes a socket left behind by a ralph that is no
+// longer running, and refuses to take over one that still answers.
+func removeStaleSocket(path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return nil
+	}
+	if c, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		c.Close()
+		return fmt.Errorf("%s is in use by another ralph", path)
+	}
+	return os.Remove(path)
+}
+
+// Addr returns the address the server listens on, in the form Listen
+// takes, with the port filled in.
+func (s *Server) Addr() string {
+	if s == nil {
+		return ""
+	}
+	return s.addr
+}
+
+// Serve starts answering requests through ctl.
+func (s *Server) Serve(ctl Controller) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.ctl = ctl
+	s.mu.Unlock()
+}
+
+// Publish sends an event to the /events clients. It does not block.
+func (s *Server) Publish(name string, data any) {
+	if s == nil {
+		return
+	}
+	s.events.publish(name, data)
+}
+
+// Close stops the server and ends open event streams.
+func (s *Server) Close() error {
+	if s == nil {
+		return nil
+	}
+	s.cancel()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	err := s.srv.Shutdown(ctx)
+	if s.socket != "" {
+		os.Remove(s.socket)
+	}
+	if s.tokenPath != "" {
+		os.Remove(s.tokenPath)
+	}
+	return err
+}
+
+func (s *Server) controller(w http.ResponseWriter) Controller {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ctl == nil {
+		writeError(w, http.StatusServiceUnavailable, errors.New("ralph is starting"))
+	}
+	return s.ctl
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	ctl := s.controller(w)
+	if ctl == nil {
+		return
+	}
+	v, err := ctl.Status()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+func (s *Server) handleStories(w http.ResponseWriter, r *http.Request) {
+	ctl := s.controller(w)
+	if ctl == nil {
+		return
+	}
+	v, err := ctl.Stories()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+func (s *Server) handleControl(a Action) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctl := s.controller(w)
+		if ctl == nil {
+			return
+		}
+		var note string
+		if a == Note {
+			var err error
+			if note, err = readNote(r); err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+		}
+		msg, err := ctl.Control(a, note)
+		switch {
+		case errors.Is(err, ErrConflict):
+			writeError(w, http.StatusConflict, err)
+		case err != nil:
+			writeError(w, http.StatusInternalServerError, err)
+		default:
+			writeJSON(w, http.StatusOK, map[string]string{"message": msg})
+		}
+	}
+}
+
+// readNote takes the note from the JSON body {"note": "..."}.
+func readNote(r *http.Request) (string, error) {
+	var in struct {
+		Note string `json:"note"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 64<<10)).Decode(&in); err != nil {
+		return "", fmt.Errorf("decoding note: %w", err)
+	}
+	note := in.Note
+	if note = strings.TrimSpace(note); note == "" {
+		return "", errors.New("empty note")
+	}
+	return note, nil
+}
+
+// handleEvents streams events as they are published. A client sending
+// Last-Event-ID first gets the buffered events after that ID; 0 replays
+// the whole buffer.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming not supported"))
+		return
+	}
+	lastID := -1
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			lastID = n
+		}
+	}
+	c, missed := s.events.subscribe(lastID)
+	defer s.events.unsubscribe(c)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	for _, ev := range missed {
+		writeEvent(w, ev)
+	}
+	flusher.Flush()
+
+	keepalive := time.NewTicker(15 * time.Second)
+	defer keepalive.Stop()
+	for {
+		select {
+		case ev := <-c:
+			writeEvent(w, ev)
+		case <-keepalive.C:
+			io.WriteString(w, ": keepalive\n\n")
+		case <-r.Context().Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+func writeEvent(w io.Writer, ev event) {
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.id, ev.name, ev.data)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type fakeController struct {
+	mu    sync.Mutex
+	notes []string
+}
+
+func (f *fakeController) Status() (any, error)  { return Status{State: "running", Iteration: 2}, nil }
+func (f *fakeController) Stories() (any, error) { return map[string]any{"userStories": []any{}}, nil }
+
+func (f *fakeController) Control(a Action, note string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if a == Note {
+		f.notes = append(f.notes, note)
+	}
+	return string(a) + "d", nil
+}
+
+func serve(t *testing.T, addr string) (*Server, *fakeController, string) {
+	t.Helper()
+	dir := t.TempDir()
+	s, err := Listen(addr, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	ctl := &fakeController{}
+	s.Serve(ctl)
+	return s, ctl, dir
+}
+
+func TestTCPNeedsToken(t *testing.T) {
+	s, ctl, dir := serve(t, "127.0.0.1:0")
+
+	fi, err := os.Stat(TokenPath(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0o600 {
+		t.Errorf("token file mode = %v, want 0600", fi.Mode().Perm())
+	}
+
+	c := NewClient(s.Addr(), dir)
+	st, err := c.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.State != "running" || st.Iteration != 2 {
+		t.Errorf("status = %+v", st)
+	}
+	if _, err := c.Control(context.Background(), Note, "Use the date helpers"); err != nil {
+		t.Fatal(err)
+	}
+	if len(ctl.notes) != 1 || ctl.notes[0] != "Use the date helpers" {
+		t.Errorf("notes = %q", ctl.notes)
+	}
+
+	if _, err := NewClient(s.Addr(), t.TempDir()).Status(context.Background()); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("status without the token: %v, want 401", err)
+	}
+
+	s.Close()
+	if _, err := os.Stat(TokenPath(dir)); !os.IsNotExist(err) {
+		t.Errorf("token file left after Close: %v", err)
+	}
+}
+
+func TestRefusesBrowsers(t *testing.T) {
+	s, ctl, dir := serve(t, "127.0.0.1:0")
+	data, _ := os.ReadFile(TokenPath(dir))
+	token := strings.TrimSpace(string(data))
+
+	tests := []struct {
+		name   string
+		method string
+		header map[string]string
+		host   string
+		body   string
+		want   int
+	}{
+		{"origin", "POST", map[string]string{"Origin": "https://evil.example", "Content-Type": "application/json"}, "", `{}`, http.StatusForbidden},
+		{"rebound host", "GET", nil, "evil.example:80", "", http.StatusForbidden},
+		{"form post", "POST", map[string]string{"Content-Type": "application/x-www-form-urlencoded"}, "", "note=hi", http.StatusUnsupportedMediaType},
+		{"text note", "POST", map[string]string{"Content-Type": "text/plain"}, "", "hi", http.StatusUnsupportedMediaType},
+		{"json note", "POST", map[string]string{"Content-Type": "application/json; charset=utf-8"}, "", `{"note":"hi"}`, http.StatusOK},
+		{"localhost", "GET", nil, "localhost", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		path := "/status"
+		if tt.method == "POST" {
+			path = "/note"
+		}
+		req, err := http.NewRequest(tt.method, "http://"+s.Addr()+path, strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		for k, v := range tt.header {
+			req.Header.Set(k, v)
+		}
+		if tt.host != "" {
+			req.Host = tt.host
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s: status %d, want %d", tt.name, resp.StatusCode, tt.want)
+		}
+	}
+	if len(ctl.notes) != 1 {
+		t.Errorf("notes = %q, want only the JSON one", ctl.notes)
+	}
+}
+
+func TestUnixSocket(t *testing.T) {
+	s, _, dir := serve(t, "unix:.ralph-tui/ralph.sock")
+	path := filepath.Join(dir, ".ralph-tui", "ralph.sock")
+	if s.Addr() != "unix:"+path {
+		t.Errorf("addr = %q", s.Addr())
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm()&0o077 != 0 {
+		t.Errorf("socket mode = %v, want no access for group and others", fi.Mode().Perm())
+	}
+	if _, err := os.Stat(TokenPath(dir)); !os.IsNotExist(err) {
+		t.Errorf("token written for a socket: %v", err)
+	}
+	if _, err := NewClient(s.Addr(), dir).Control(context.Background(), Pause, ""); err != nil {
+		t.Fatal(err)
+	}
+}
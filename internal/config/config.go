@@ -1,7 +1,6 @@
 package config
 
 import (
-	"fmt"
 	"os"
 	"path/filepath"
 
@@ -12,53 +11,182 @@ type Config struct {
 	ConfigVersion         string         `toml:"configVersion"`
 	MaxIterations         int            `toml:"maxIterations"`
 	Agent                 string         `toml:"agent"`
+	Model                 string         `toml:"model"`
 	Tracker               string         `toml:"tracker"`
 	AutoCommit            bool           `toml:"autoCommit"`
+	StepMode              bool           `toml:"stepMode"`
+	Guidance              string         `toml:"guidance"` // prompt or progress
 	SubagentTracingDetail string         `toml:"subagentTracingDetail"`
 	AgentOptions          map[string]any `toml:"agentOptions"`
 	TrackerOptions        map[string]any `toml:"trackerOptions"`
+	Sync                  SyncConfig     `toml:"sync"`
+	Hooks                 HooksConfig    `toml:"hooks"`
+	Webhooks              []Webhook      `toml:"webhooks"`
+	Notify                NotifyConfig   `toml:"notify"`
+	Server                ServerConfig   `toml:"server"`
+	Profile               string         `toml:"profile"`  // profile used when --profile is not given
+	Profiles              map[string]any `toml:"profiles"` // [profiles.<name>] tables of overrides
+
+	origins    map[string]string // key -> where its value came from, see Origin
+	migrations []*Migration
+	warnings   []string
+}
+
+// SyncConfig configures `ralph sync`, which keeps the PRD file in step with
+// another tracker while the loop itself runs on the PRD.
+type SyncConfig struct {
+	Tracker  string `toml:"tracker"`  // tracker to sync with, e.g. github
+	OnStart  bool   `toml:"onStart"`  // sync before the loop starts
+	OnFinish bool   `toml:"onFinish"` // sync after the TUI exits
 }
 
 func DefaultConfig() *Config {
 	return &Config{
-		ConfigVersion:         "2.1",
+		ConfigVersion:         Version,
 		MaxIterations:         10,
 		Agent:                 "amp",
 		Tracker:               "json",
 		AutoCommit:            true,
+		Guidance:              "prompt",
 		SubagentTracingDetail: "full",
 		AgentOptions:          map[string]any{},
 		TrackerOptions:        map[string]any{},
+		Profiles:              map[string]any{},
+		Hooks:                 HooksConfig{Timeout: 60},
+		Notify: NotifyConfig{
+			Events:   []string{"complete", "budgetExceeded", "blocked", "agentCrash"},
+			Terminal: "bell",
+		},
 	}
 }
 
-// Load reads .ralph-tui/config.toml from the given project dir.
-// If missing, returns defaults.
+// HooksConfig holds shell commands run on loop events (see package
+// hooks). An empty command is not run.
+type HooksConfig struct {
+	Timeout        int    `toml:"timeout"` // seconds per hook
+	SessionStart   string `toml:"sessionStart"`
+	SessionEnd     string `toml:"sessionEnd"`
+	IterationStart string `toml:"iterationStart"` // non-zero exit vetoes the iteration
+	IterationEnd   string `toml:"iterationEnd"`
+	StoryPassed    string `toml:"storyPassed"`
+	StoryFailed    string `toml:"storyFailed"`
+	Complete       string `toml:"complete"`
+	BudgetExceeded string `toml:"budgetExceeded"` // maxIterations reached
+	AgentCrash     string `toml:"agentCrash"`
+	Blocked        string `toml:"blocked"` // loop held for a human
+}
+
+// NotifyConfig picks the events that notify the person at the terminal,
+// and how (see package notify).
+type NotifyConfig struct {
+	Events     []string `toml:"events"`
+	Terminal   string   `toml:"terminal"`   // osc9, osc777, bell or off
+	NotifySend bool     `toml:"notifySend"` // also run notify-send
+}
+
+// ServerConfig enables the HTTP API of a running session (see package
+// api).
+type ServerConfig struct {
+	Listen string `toml:"listen"` // "127.0.0.1:7777" or "unix:<path>"; empty: off
+}
+
+// Webhook is one [[webhooks]] endpoint that loop events are posted to
+// (see package webhook). A later config layer replaces the whole list.
+type Webhook struct {
+	URL       string   `toml:"url"`
+	Secret    string   `toml:"secret"`    // HMAC-SHA256 key for X-Ralph-Signature
+	SecretEnv string   `toml:"secretEnv"` // read the secret from this variable
+	Events    []string `toml:"events"`    // events to send; empty sends all
+	Retries   int      `toml:"retries"`   // attempts after the first, default 3
+	Timeout   int      `toml:"timeout"`   // seconds per attempt, default 10
+}
+
+// Load resolves the configuration for a project, each layer overriding
+// the one before: built-in defaults, the global config (see GlobalPath),
+// .ralph-tui/config.toml in the project, the selected profile and RALPH_*
+// environment variables. CLI flags are applied on top by the caller with
+// Set.
 func Load(projectDir string) (*Config, error) {
-	path := filepath.Join(projectDir, ".ralph-tui", "config.toml")
+	return LoadProfile(projectDir, "")
+}
+
+// LoadProfile is Load with the profile given by --profile. An empty
+// profile falls back to $RALPH_PROFILE, then to the profile key.
+func LoadProfile(projectDir, profile string) (*Config, error) {
 	cfg := DefaultConfig()
+	if path := GlobalPath(); path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.loadFile(ProjectPath(projectDir)); err != nil {
+		return nil, err
+	}
+	if err := cfg.selectProfile(profile); err != nil {
+		return nil, err
+	}
+	if err := cfg.loadEnv(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
 
-	data, err := os.ReadFile(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return cfg, nil
+// GlobalPath returns the user's config file,
+// $XDG_CONFIG_HOME/ralph/config.toml (default ~/.config/ralph/config.toml).
+func GlobalPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
 		}
-		return nil, fmt.Errorf("reading config: %w", err)
+		dir = filepath.Join(home, ".config")
 	}
+	return filepath.Join(dir, "ralph", "config.toml")
+}
+
+// ProjectPath returns the project's config file.
+func ProjectPath(projectDir string) string {
+	return filepath.Join(projectDir, ".ralph-tui", "config.toml")
+}
+
+// AgentOptionsFor returns the agentOptions that apply to the named agent:
+// top-level scalar keys, overridden by the keys in [agentOptions.<name>].
+func (c *Config) AgentOptionsFor(name string) map[string]any {
+	return optionsFor(c.AgentOptions, name)
+}
+
+// TrackerOptionsFor returns the trackerOptions that apply to the named
+// tracker, resolved the same way as AgentOptionsFor.
+func (c *Config) TrackerOptionsFor(name string) map[string]any {
+	return optionsFor(c.TrackerOptions, name)
+}
 
-	if _, err := toml.Decode(string(data), cfg); err != nil {
-		return nil, fmt.Errorf("parsing config.toml: %w", err)
+func optionsFor(all map[string]any, name string) map[string]any {
+	opts := map[string]any{}
+	for k, v := range all {
+		if _, isTable := v.(map[string]any); !isTable {
+			opts[k] = v
+		}
 	}
-	return cfg, nil
+	if table, ok := all[name].(map[string]any); ok {
+		for k, v := range table {
+			opts[k] = v
+		}
+	}
+	return opts
 }
 
 // Save writes the config to .ralph-tui/config.toml.
 func (c *Config) Save(projectDir string) error {
-	dir := filepath.Join(projectDir, ".ralph-tui")
-	if err := os.MkdirAll(dir, 0755); err != nil {
+	return c.SaveTo(ProjectPath(projectDir))
+}
+
+// SaveTo writes the config to path.
+func (c *Config) SaveTo(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
 		return err
 	}
-	path := filepath.Join(dir, "config.toml")
 	f, err := os.Create(path)
 	if err != nil {
 		return err
@@ -66,3 +194,10 @@ func (c *Config) Save(projectDir string) error {
 	defer f.Close()
 	return toml.NewEncoder(f).Encode(c)
 }
+
+// Migrations returns the config files Load read that were written by an
+// older ralph and can be rewritten with Migration.Apply.
+func (c *Config) Migrations() []*Migration { return c.migrations }
+
+// Warnings returns problems found while loading, such as unknown keys.
+func (c *Config) Warnings() []string { return c.warnings }
@@ -0,0 +1,310 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/BurntSushi/toml"
+)
+
+// loadFile applies a config file on top of c. Only keys set in the file
+// change; option tables are merged key by key. A missing file is skipped.
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("reading config: %w", err)
+	}
+
+	text, m, err := migrate(path, string(data))
+	if err != nil {
+		return err
+	}
+	if m != nil {
+		c.migrations = append(c.migrations, m)
+	}
+	return c.apply(text, path)
+}
+
+// apply decodes TOML on top of c and records origin for the keys it sets.
+func (c *Config) apply(text, origin string) error {
+	agentOpts, trackerOpts, profiles := c.AgentOptions, c.TrackerOptions, c.Profiles
+	c.AgentOptions, c.TrackerOptions, c.Profiles = nil, nil, nil
+	md, err := toml.Decode(text, c)
+	c.AgentOptions = mergeOptions(agentOpts, c.AgentOptions)
+	c.TrackerOptions = mergeOptions(trackerOpts, c.TrackerOptions)
+	c.Profiles = mergeOptions(profiles, c.Profiles)
+	if err != nil {
+		return fmt.Errorf("parsing %s: %w", origin, err)
+	}
+	for _, k := range md.Keys() {
+		c.setOrigin(k.String(), origin)
+	}
+	for _, k := range md.Undecoded() {
+		switch k[0] {
+		case "agentOptions", "trackerOptions":
+			continue // free-form, checked by the agent or tracker
+		case "profiles":
+			continue // checked when the profile is applied
+		}
+		c.warnings = append(c.warnings, fmt.Sprintf("%s: unknown key %q", origin, k.String()))
+	}
+	return nil
+}
+
+// selectProfile picks the profile to use, from the --profile flag (name),
+// $RALPH_PROFILE or the profile key, and applies it on top of c.
+func (c *Config) selectProfile(name string) error {
+	switch env := EnvName("profile"); {
+	case name != "":
+		c.Set("profile", name, "--profile")
+	case os.Getenv(env) != "":
+		c.Set("profile", os.Getenv(env), "$"+env)
+	}
+	if c.Profile == "" {
+		return nil
+	}
+
+	table, ok := c.Profiles[c.Profile].(map[string]any)
+	if !ok {
+		names := make([]string, 0, len(c.Profiles))
+		for n := range c.Profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			return fmt.Errorf("unknown profile %q: no [profiles.<name>] tables are defined", c.Profile)
+		}
+		return fmt.Errorf("unknown profile %q. Must be one of: %s", c.Profile, strings.Join(names, ", "))
+	}
+	delete(table, "profile")
+	var b strings.Builder
+	if err := toml.NewEncoder(&b).Encode(table); err != nil {
+		return fmt.Errorf("profile %s: %w", c.Profile, err)
+	}
+	return c.apply(b.String(), "[profiles."+c.Profile+"]")
+}
+
+// loadEnv applies RALPH_* environment variables, one per scalar key:
+// maxIterations is RALPH_MAX_ITERATIONS, sync.onStart RALPH_SYNC_ON_START.
+func (c *Config) loadEnv() error {
+	for _, f := range c.fields() {
+		if f.key == "profile" {
+			continue // see selectProfile
+		}
+		name := EnvName(f.key)
+		if v, ok := os.LookupEnv(name); ok {
+			if err := c.Set(f.key, v, "$"+name); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// EnvName returns the environment variable that overrides a key.
+func EnvName(key string) string {
+	var b strings.Builder
+	b.WriteString("RALPH_")
+	for i, r := range key {
+		switch {
+		case r == '.':
+			b.WriteByte('_')
+		case unicode.IsUpper(r) && i > 0 && key[i-1] != '.':
+			b.WriteByte('_')
+			b.WriteRune(r)
+		default:
+			b.WriteRune(unicode.ToUpper(r))
+		}
+	}
+	return b.String()
+}
+
+// Set parses value into the scalar key (e.g. "model" or "sync.onStart")
+// and records origin as where it came from, e.g. "--model". A list such
+// as notify.events is given comma-separated.
+func (c *Config) Set(key, value, origin string) error {
+	for _, f := range c.fields() {
+		if f.key != key {
+			continue
+		}
+		switch f.v.Kind() {
+		case reflect.String:
+			f.v.SetString(value)
+		case reflect.Int:
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("%s: %q is not a number", key, value)
+			}
+			f.v.SetInt(int64(n))
+		case reflect.Bool:
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("%s: %q is not true or false", key, value)
+			}
+			f.v.SetBool(b)
+		case reflect.Slice:
+			var list []string
+			for _, v := range strings.Split(value, ",") {
+				if v = strings.TrimSpace(v); v != "" {
+					list = append(list, v)
+				}
+			}
+			f.v.Set(reflect.ValueOf(list))
+		}
+		c.setOrigin(key, origin)
+		return nil
+	}
+	return fmt.Errorf("unknown config key %q", key)
+}
+
+// Origin returns where the value of key came from: a config file path,
+// $RALPH_... for an environment variable, a flag, or "default".
+func (c *Config) Origin(key string) string {
+	if o, ok := c.origins[key]; ok {
+		return o
+	}
+	return "default"
+}
+
+func (c *Config) setOrigin(key, origin string) {
+	if c.origins == nil {
+		c.origins = map[string]string{}
+	}
+	c.origins[key] = origin
+}
+
+// Value is one effective setting.
+type Value struct {
+	Key    string
+	Value  string // TOML syntax
+	Origin string
+}
+
+// Values lists every effective setting in file order, followed by the
+// agent and tracker options and the webhooks.
+func (c *Config) Values() []Value {
+	var vals []Value
+	for _, f := range c.fields() {
+		vals = append(vals, Value{f.key, formatValue(f.v.Interface()), c.Origin(f.key)})
+	}
+	for _, opts := range []struct {
+		key string
+		m   map[string]any
+	}{{"agentOptions", c.AgentOptions}, {"trackerOptions", c.TrackerOptions}} {
+		vals = c.optionValues(vals, opts.key, opts.m)
+	}
+	for i, w := range c.Webhooks {
+		prefix := fmt.Sprintf("webhooks[%d].", i)
+		for _, kv := range [][2]string{
+			{"url", strconv.Quote(w.URL)},
+			{"secret", strconv.Quote(w.Secret)},
+			{"secretEnv", strconv.Quote(w.SecretEnv)},
+			{"events", formatValue(w.Events)},
+			{"retries", strconv.Itoa(w.Retries)},
+			{"timeout", strconv.Itoa(w.Timeout)},
+		} {
+			vals = append(vals, Value{prefix + kv[0], kv[1], c.Origin("webhooks")})
+		}
+	}
+	return vals
+}
+
+func (c *Config) optionValues(vals []Value, prefix string, m map[string]any) []Value {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		key := prefix + "." + k
+		if sub, ok := m[k].(map[string]any); ok {
+			vals = c.optionValues(vals, key, sub)
+			continue
+		}
+		vals = append(vals, Value{key, formatValue(m[k]), c.Origin(key)})
+	}
+	return vals
+}
+
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		return strconv.Quote(v)
+	case []string:
+		parts := make([]string, len(v))
+		for i, e := range v {
+			parts[i] = strconv.Quote(e)
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	case []any:
+		parts := make([]string, len(v))
+		for i, e := range v {
+			parts[i] = formatValue(e)
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+type field struct {
+	key string
+	v   reflect.Value
+}
+
+// fields lists the scalar settings of c, and lists of strings, by their
+// TOML key, descending into tables such as [sync].
+func (c *Config) fields() []field {
+	var out []field
+	var walk func(prefix string, v reflect.Value)
+	walk = func(prefix string, v reflect.Value) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			tag := t.Field(i).Tag.Get("toml")
+			if tag == "" {
+				continue
+			}
+			fv := v.Field(i)
+			switch fv.Kind() {
+			case reflect.Struct:
+				walk(prefix+tag+".", fv)
+			case reflect.String, reflect.Int, reflect.Bool:
+				out = append(out, field{prefix + tag, fv})
+			case reflect.Slice:
+				if fv.Type().Elem().Kind() == reflect.String {
+					out = append(out, field{prefix + tag, fv})
+				}
+			}
+		}
+	}
+	walk("", reflect.ValueOf(c).Elem())
+	return out
+}
+
+// mergeOptions returns base with over applied on top, merging nested
+// tables so a project can override one option of a globally configured
+// agent without repeating the rest.
+func mergeOptions(base, over map[string]any) map[string]any {
+	out := make(map[string]any, len(base)+len(over))
+	for k, v := range base {
+		out[k] = v
+	}
+	for k, v := range over {
+		if sub, ok := v.(map[string]any); ok {
+			if prev, ok := out[k].(map[string]any); ok {
+				out[k] = mergeOptions(prev, sub)
+				continue
+			}
+		}
+		out[k] = v
+	}
+	return out
+}
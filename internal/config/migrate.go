@@ -0,0 +1,143 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Version is the configVersion this ralph writes and understands.
+const Version = "2.1"
+
+// migrations rewrite the raw keys of a config file from the layout of one
+// version to the next. A version without an entry has the same layout as
+// the one after it; only its configVersion changes.
+var migrations = map[string]func(raw map[string]any) []string{}
+
+// Migration is a config file written by an older ralph. Load reads it as
+// if it had been migrated; Apply rewrites the file.
+type Migration struct {
+	Path     string
+	From, To string
+	Changes  []string // what the migration changes, one line each
+
+	text    string // the migrated file
+	patched bool   // text is the original with only configVersion changed
+}
+
+// KeepsComments reports whether Apply leaves the rest of the file as it
+// is. Migrations that move keys re-encode it, dropping comments.
+func (m *Migration) KeepsComments() bool { return m.patched }
+
+// Apply writes the migrated file over the original, keeping the original
+// next to it as config.toml.bak. Only the keys the file sets are written,
+// so defaults and other layers still apply as before.
+func (m *Migration) Apply() error {
+	data, err := os.ReadFile(m.Path)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(m.Path+".bak", data, 0644); err != nil {
+		return fmt.Errorf("backing up %s: %w", m.Path, err)
+	}
+	return os.WriteFile(m.Path, []byte(m.text), 0644)
+}
+
+// versionLine matches the top-level configVersion setting; top-level keys
+// come before any table, so the first match is the one.
+var versionLine = regexp.MustCompile(`(?m)^(\s*configVersion\s*=\s*)("[^"\n]*"|'[^'\n]*')`)
+
+// migrate checks the configVersion of a config file and brings its
+// contents up to Version. It returns the TOML to decode and, if the file
+// is older, the pending migration. A file without configVersion is taken
+// to be current; a newer one is an error.
+func migrate(path, data string) (string, *Migration, error) {
+	var raw map[string]any
+	if _, err := toml.Decode(data, &raw); err != nil {
+		return "", nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	from, _ := raw["configVersion"].(string)
+	if from == "" || from == Version {
+		return data, nil, nil
+	}
+	cmp, err := compareVersions(from, Version)
+	if err != nil {
+		return "", nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if cmp > 0 {
+		return "", nil, fmt.Errorf("%s has configVersion %s, but this ralph only understands up to %s; upgrade ralph or edit the file", path, from, Version)
+	}
+
+	m := &Migration{Path: path, From: from, To: Version}
+	versions := make([]string, 0, len(migrations))
+	for v := range migrations {
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		c, _ := compareVersions(versions[i], versions[j])
+		return c < 0
+	})
+	for _, v := range versions {
+		if c, _ := compareVersions(v, from); c >= 0 {
+			m.Changes = append(m.Changes, migrations[v](raw)...)
+		}
+	}
+	raw["configVersion"] = Version
+
+	// Only the version changes: patch it in place so comments survive.
+	if len(m.Changes) == 0 && versionLine.MatchString(data) {
+		loc := versionLine.FindStringSubmatchIndex(data)
+		m.text = data[:loc[4]] + strconv.Quote(Version) + data[loc[5]:]
+		m.patched = true
+	} else {
+		var b strings.Builder
+		if err := toml.NewEncoder(&b).Encode(raw); err != nil {
+			return "", nil, fmt.Errorf("migrating %s: %w", path, err)
+		}
+		m.text = b.String()
+	}
+	m.Changes = append(m.Changes, fmt.Sprintf("configVersion %q -> %q", from, Version))
+	return m.text, m, nil
+}
+
+// compareVersions compares two "major.minor" versions.
+func compareVersions(a, b string) (int, error) {
+	pa, err := parseVersion(a)
+	if err != nil {
+		return 0, err
+	}
+	pb, err := parseVersion(b)
+	if err != nil {
+		return 0, err
+	}
+	for i := range pa {
+		if pa[i] != pb[i] {
+			if pa[i] < pb[i] {
+				return -1, nil
+			}
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+func parseVersion(v string) ([2]int, error) {
+	var out [2]int
+	parts := strings.Split(v, ".")
+	if len(parts) > 2 {
+		return out, fmt.Errorf("invalid configVersion %q", v)
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return out, fmt.Errorf("invalid configVersion %q", v)
+		}
+		out[i] = n
+	}
+	return out, nil
+}
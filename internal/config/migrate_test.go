@@ -0,0 +1,101 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMigrationKeepsFileAndLayers(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+	global := filepath.Join(home, "ralph", "config.toml")
+	if err := os.MkdirAll(filepath.Dir(global), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(global, []byte("configVersion = \"2.1\"\nmaxIterations = 42\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	project := t.TempDir()
+	path := ProjectPath(project)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	old := "# Project settings\nconfigVersion = \"2.0\" # written by ralph 0.9\nagent = \"amp\"\n"
+	if err := os.WriteFile(path, []byte(old), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(project)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ms := cfg.Migrations()
+	if len(ms) != 1 || ms[0].Path != path || !ms[0].KeepsComments() {
+		t.Fatalf("migrations = %+v", ms)
+	}
+	if err := ms[0].Apply(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(path)
+	want := "# Project settings\nconfigVersion = \"" + Version + "\" # written by ralph 0.9\nagent = \"amp\"\n"
+	if string(data) != want {
+		t.Errorf("migrated file:\n%s\nwant:\n%s", data, want)
+	}
+	if bak, _ := os.ReadFile(path + ".bak"); string(bak) != old {
+		t.Errorf("backup:\n%s", bak)
+	}
+
+	cfg, err = Load(project)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Migrations()) != 0 {
+		t.Errorf("still pending after Apply: %+v", cfg.Migrations())
+	}
+	if cfg.MaxIterations != 42 || cfg.Agent != "amp" {
+		t.Errorf("maxIterations = %d, agent = %q; want the global 42 and the project's amp", cfg.MaxIterations, cfg.Agent)
+	}
+}
+
+func TestMigrationReencodesOwnKeys(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	migrations["2.0"] = func(raw map[string]any) []string {
+		raw["agent"] = raw["tool"]
+		delete(raw, "tool")
+		return []string{"tool -> agent"}
+	}
+	defer delete(migrations, "2.0")
+
+	project := t.TempDir()
+	path := ProjectPath(project)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("# comment\nconfigVersion = \"2.0\"\ntool = \"amp\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(project)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Agent != "amp" {
+		t.Errorf("agent = %q, want amp", cfg.Agent)
+	}
+	m := cfg.Migrations()[0]
+	if m.KeepsComments() {
+		t.Error("a migration that moves keys claims to keep comments")
+	}
+	if err := m.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if k := strings.TrimSpace(strings.SplitN(line, "=", 2)[0]); k != "agent" && k != "configVersion" {
+			t.Errorf("migrated file sets %q:\n%s", k, data)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+// Package git wraps the few git commands ralph needs to summarise what an
+// agent changed.
+package git
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Head returns the commit hash HEAD points to in dir, or "" if dir is not
+// inside a git repository or has no commits yet.
+func Head(dir string) string {
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// DiffStat returns `git diff --stat` between rev and the working tree, which
+// covers both commits made since rev and uncommitted changes.
+func DiffStat(dir, rev string) (string, error) {
+	if rev == "" {
+		return "", fmt.Errorf("no base revision (not a git repository?)")
+	}
+	out, err := exec.Command("git", "-C", dir, "diff", "--stat", rev).Output()
+	if err != nil {
+		return "", fmt.Errorf("git diff --stat: %w", err)
+	}
+	return strings.TrimRight(string(out), "\n"), nil
+}
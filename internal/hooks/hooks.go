@@ -0,0 +1,209 @@
+// Package hooks runs user-configured shell commands when things happen in
+// the loop: a session or iteration starting or ending, a story passing or
+// failing, completion, running out of iterations, the agent crashing and
+// the loop being held for a human.
+//
+// Each hook runs with `sh -c` in the project dir. It gets the event as
+// JSON on stdin and the main fields as environment variables, and is
+// killed after the configured timeout.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/zhrkvl/ralph-go/internal/config"
+)
+
+// Event names a point in the loop a hook can run at. The names match the
+// keys under [hooks] in config.toml.
+type Event string
+
+const (
+	SessionStart   Event = "sessionStart"
+	SessionEnd     Event = "sessionEnd"
+	IterationStart Event = "iterationStart"
+	IterationEnd   Event = "iterationEnd"
+	StoryPassed    Event = "storyPassed"
+	StoryFailed    Event = "storyFailed"
+	Complete       Event = "complete"
+	BudgetExceeded Event = "budgetExceeded"
+	AgentCrash     Event = "agentCrash"
+	Blocked        Event = "blocked"
+)
+
+// Events lists every event in the order they are documented.
+var Events = []Event{
+	SessionStart, SessionEnd, IterationStart, IterationEnd, StoryPassed,
+	StoryFailed, Complete, BudgetExceeded, AgentCrash, Blocked,
+}
+
+// Payload is what a hook is told about the event. It is written to the
+// hook's stdin as JSON.
+type Payload struct {
+	Event         Event     `json:"event"`
+	Time          time.Time `json:"time"`
+	SessionID     string    `json:"sessionId"`
+	ProjectDir    string    `json:"projectDir"`
+	Iteration     int       `json:"iteration"`
+	MaxIterations int       `json:"maxIterations"`
+	StoryID       string    `json:"storyId,omitempty"`
+	StoryTitle    string    `json:"storyTitle,omitempty"`
+	Status        string    `json:"status,omitempty"` // session status
+	Reason        string    `json:"reason,omitempty"` // e.g. the agent's exit error
+}
+
+// Title names the project the event is from, e.g. "ralph [myapp]".
+func (p Payload) Title() string {
+	if p.ProjectDir == "" {
+		return "ralph"
+	}
+	return "ralph [" + filepath.Base(p.ProjectDir) + "]"
+}
+
+// Summary describes the event in a short sentence for people, e.g.
+// "US-002 Login page passed".
+func (p Payload) Summary() string {
+	story := strings.TrimSpace(p.StoryID + " " + p.StoryTitle)
+	switch p.Event {
+	case SessionStart:
+		return fmt.Sprintf("session started (up to %d iterations)", p.MaxIterations)
+	case SessionEnd:
+		return "session ended: " + p.Status
+	case IterationStart:
+		return fmt.Sprintf("iteration %d/%d started on %s", p.Iteration, p.MaxIterations, story)
+	case IterationEnd:
+		return fmt.Sprintf("iteration %d/%d finished", p.Iteration, p.MaxIterations)
+	case StoryPassed:
+		return story + " passed"
+	case StoryFailed:
+		return fmt.Sprintf("%s still not passing after iteration %d", story, p.Iteration)
+	case Complete:
+		return "all stories passed"
+	case BudgetExceeded:
+		return fmt.Sprintf("stopped after %d iterations without completing", p.Iteration)
+	case AgentCrash:
+		return "agent crashed: " + p.Reason
+	case Blocked:
+		return "blocked, needs a human: " + p.Reason
+	}
+	return string(p.Event)
+}
+
+// Runner runs the configured hooks. A nil Runner runs nothing.
+type Runner struct {
+	commands   map[Event]string
+	timeout    time.Duration
+	projectDir string
+}
+
+// New returns a Runner for the [hooks] config, or nil if no hook is set.
+func New(cfg config.HooksConfig, projectDir string) *Runner {
+	r := &Runner{
+		commands: map[Event]string{
+			SessionStart:   cfg.SessionStart,
+			SessionEnd:     cfg.SessionEnd,
+			IterationStart: cfg.IterationStart,
+			IterationEnd:   cfg.IterationEnd,
+			StoryPassed:    cfg.StoryPassed,
+			StoryFailed:    cfg.StoryFailed,
+			Complete:       cfg.Complete,
+			BudgetExceeded: cfg.BudgetExceeded,
+			AgentCrash:     cfg.AgentCrash,
+			Blocked:        cfg.Blocked,
+		},
+		timeout:    time.Duration(cfg.Timeout) * time.Second,
+		projectDir: projectDir,
+	}
+	for e, cmd := range r.commands {
+		if strings.TrimSpace(cmd) == "" {
+			delete(r.commands, e)
+		}
+	}
+	if len(r.commands) == 0 {
+		return nil
+	}
+	if r.timeout <= 0 {
+		r.timeout = 60 * time.Second
+	}
+	return r
+}
+
+// Has reports whether a hook is configured for e.
+func (r *Runner) Has(e Event) bool {
+	if r == nil {
+		return false
+	}
+	_, ok := r.commands[e]
+	return ok
+}
+
+// Run runs the hook for p.Event, if any, and waits for it. It returns an
+// error if the hook exits non-zero or times out; the error ends with the
+// last line the hook printed.
+func (r *Runner) Run(p Payload) error {
+	if !r.Has(p.Event) {
+		return nil
+	}
+	if p.Time.IsZero() {
+		p.Time = time.Now().UTC()
+	}
+	if p.ProjectDir == "" {
+		p.ProjectDir = r.projectDir
+	}
+	input, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "sh", "-c", r.commands[p.Event])
+	cmd.Dir = r.projectDir
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Env = append(os.Environ(),
+		"RALPH_EVENT="+string(p.Event),
+		"RALPH_SESSION_ID="+p.SessionID,
+		"RALPH_STORY_ID="+p.StoryID,
+		"RALPH_ITERATION="+strconv.Itoa(p.Iteration),
+		"RALPH_STATUS="+p.Status,
+	)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	// Kill the whole process group on timeout, so a hook's children do
+	// not keep it alive.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error { return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL) }
+	cmd.WaitDelay = time.Second
+
+	err = cmd.Run()
+	if err == nil {
+		return nil
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("timed out after %s", r.timeout)
+	}
+	if last := lastLine(out.String()); last != "" {
+		return fmt.Errorf("%s hook: %w: %s", p.Event, err, last)
+	}
+	return fmt.Errorf("%s hook: %w", p.Event, err)
+}
+
+func lastLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		s = s[i+1:]
+	}
+	return strings.TrimSpace(s)
+}
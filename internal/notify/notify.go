@@ -0,0 +1,135 @@
+// Package notify tells the person running ralph that the loop needs them:
+// through the terminal, with an OSC 9 or OSC 777 desktop notification or
+// the bell, and optionally with notify-send. It is meant for a ralph left
+// running in a background tab or tmux pane.
+package notify
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zhrkvl/ralph-go/internal/config"
+	"github.com/zhrkvl/ralph-go/internal/hooks"
+)
+
+// Terminals lists the values of notify.terminal.
+var Terminals = []string{"osc9", "osc777", "bell", "off"}
+
+// Notifier sends notifications for the configured events. A nil Notifier
+// sends nothing.
+type Notifier struct {
+	events     []hooks.Event
+	terminal   string
+	notifySend bool
+	tmux       bool // wrap escape sequences so tmux passes them through
+
+	mu  sync.Mutex
+	out io.Writer
+}
+
+// New returns a Notifier for the [notify] config, or nil if it would
+// never notify.
+func New(cfg config.NotifyConfig) (*Notifier, error) {
+	n := &Notifier{
+		terminal:   cfg.Terminal,
+		notifySend: cfg.NotifySend,
+		tmux:       os.Getenv("TMUX") != "",
+		out:        os.Stdout,
+	}
+	if n.terminal == "" {
+		n.terminal = "bell"
+	}
+	if !slices.Contains(Terminals, n.terminal) {
+		return nil, fmt.Errorf("notify.terminal must be one of %s, not %q", strings.Join(Terminals, ", "), cfg.Terminal)
+	}
+	for _, name := range cfg.Events {
+		if !slices.Contains(hooks.Events, hooks.Event(name)) {
+			return nil, fmt.Errorf("notify.events: unknown event %q", name)
+		}
+		n.events = append(n.events, hooks.Event(name))
+	}
+	if len(n.events) == 0 || (n.terminal == "off" && !n.notifySend) {
+		return nil, nil
+	}
+	return n, nil
+}
+
+// SetOutput sets where the terminal sequences go instead of stdout. While
+// a TUI draws on the terminal, w must be the writer its renderer uses and
+// keep each Write whole, so a sequence never lands inside a frame.
+func (n *Notifier) SetOutput(w io.Writer) {
+	if n == nil {
+		return
+	}
+	n.mu.Lock()
+	n.out = w
+	n.mu.Unlock()
+}
+
+// Wants reports whether e is one of the events to notify about.
+func (n *Notifier) Wants(e hooks.Event) bool {
+	return n != nil && slices.Contains(n.events, e)
+}
+
+// Notify sends the notification for p if its event is wanted. Only
+// notify-send can fail.
+func (n *Notifier) Notify(p hooks.Payload) error {
+	if !n.Wants(p.Event) {
+		return nil
+	}
+	title, body := clean(p.Title()), clean(p.Summary())
+
+	var seq string
+	switch n.terminal {
+	case "osc9":
+		seq = "\x1b]9;" + title + ": " + body + "\a"
+	case "osc777":
+		seq = "\x1b]777;notify;" + strings.ReplaceAll(title, ";", ",") + ";" + body + "\a"
+	case "bell":
+		seq = "\a"
+	}
+	if seq != "" {
+		if n.tmux && seq != "\a" {
+			seq = "\x1bPtmux;" + strings.ReplaceAll(seq, "\x1b", "\x1b\x1b") + "\x1b\\"
+		}
+		n.mu.Lock()
+		io.WriteString(n.out, seq)
+		n.mu.Unlock()
+	}
+
+	if !n.notifySend {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	urgency := "normal"
+	if p.Event == hooks.AgentCrash || p.Event == hooks.BudgetExceeded {
+		urgency = "critical"
+	}
+	out, err := exec.CommandContext(ctx, "notify-send", "--app-name=ralph", "--urgency="+urgency, title, body).CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("notify-send: %w: %s", err, msg)
+		}
+		return fmt.Errorf("notify-send: %w", err)
+	}
+	return nil
+}
+
+// clean drops control characters, which would end or corrupt an escape
+// sequence.
+func clean(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || (r >= 0x7f && r < 0xa0) {
+			return -1
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,115 @@
+// Package plan turns a free-form spec into a PRD by asking the agent to
+// write one.
+package plan
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/zhrkvl/ralph-go/internal/prd"
+)
+
+//go:embed prompt.md
+var promptTemplate string
+
+// Prompt returns the planning prompt for spec.
+func Prompt(spec string) []byte {
+	return []byte(strings.Replace(promptTemplate, "{{spec}}", strings.TrimSpace(spec), 1))
+}
+
+var (
+	prdTag    = regexp.MustCompile(`(?s)<prd>(.*?)</prd>`)
+	jsonFence = regexp.MustCompile("(?s)```(?:json)?\\s*\n(\\{.*?\\})\\s*```")
+)
+
+// Extract finds the PRD JSON in the agent's output: the last <prd> block,
+// else the last ```json fence, else everything from the first "{" to the
+// last "}".
+func Extract(output string) ([]byte, error) {
+	if m := prdTag.FindAllStringSubmatch(output, -1); m != nil {
+		return []byte(strings.TrimSpace(m[len(m)-1][1])), nil
+	}
+	if m := jsonFence.FindAllStringSubmatch(output, -1); m != nil {
+		return []byte(m[len(m)-1][1]), nil
+	}
+	start, end := strings.Index(output, "{"), strings.LastIndex(output, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no PRD found in the agent's output")
+	}
+	return []byte(output[start : end+1]), nil
+}
+
+// Build parses the extracted JSON and fills in what the loop needs but the
+// agent may have left out or got wrong: unique IDs, distinct priorities in
+// listed order when they collide, passes=false and a branch name derived
+// from specPath.
+func Build(data []byte, specPath string) (*prd.PRD, error) {
+	var p prd.PRD
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("agent output is not a valid PRD: %w", err)
+	}
+	if len(p.UserStories) == 0 {
+		return nil, fmt.Errorf("agent produced no user stories")
+	}
+
+	seen := make(map[string]bool)
+	priorities := make(map[int]bool)
+	renumber := false
+	for i := range p.UserStories {
+		s := &p.UserStories[i]
+		if s.ID == "" || seen[s.ID] {
+			s.ID = ""
+		} else {
+			seen[s.ID] = true
+		}
+		if s.Priority <= 0 || priorities[s.Priority] {
+			renumber = true
+		}
+		priorities[s.Priority] = true
+		s.Passes = false
+		s.Notes = ""
+		s.Iterations = 0
+	}
+	for i := range p.UserStories {
+		if s := &p.UserStories[i]; s.ID == "" {
+			s.ID = p.NextID()
+		}
+		if renumber {
+			p.UserStories[i].Priority = i + 1
+		}
+	}
+
+	if p.Name == "" {
+		p.Name = strings.TrimSuffix(filepath.Base(specPath), filepath.Ext(specPath))
+	}
+	if p.BranchName == "" {
+		p.BranchName = BranchName(p.Name)
+	}
+	return &p, nil
+}
+
+// BranchName derives a branch name such as ralph/my-project from a
+// project name.
+func BranchName(name string) string {
+	return "ralph/" + slug(name)
+}
+
+func slug(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+			dash = false
+		case !dash && b.Len() > 0:
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
@@ -0,0 +1,155 @@
+package prd
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// Update re-reads the PRD at path, applies fn and saves the result. Edits
+// are made against the latest on-disk state, not a stale in-memory copy,
+// so changes the agent made in the meantime are kept.
+func Update(path string, fn func(*PRD) error) (*PRD, error) {
+	p, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := fn(p); err != nil {
+		return nil, err
+	}
+	if err := p.Save(path); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Index returns the position of the story with the given ID, or -1.
+func (p *PRD) Index(id string) int {
+	for i, s := range p.UserStories {
+		if s.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// Story returns the story with the given ID, or an error if there is none.
+func (p *PRD) Story(id string) (*UserStory, error) {
+	i := p.Index(id)
+	if i < 0 {
+		return nil, fmt.Errorf("story %s not found", id)
+	}
+	return &p.UserStories[i], nil
+}
+
+// AddNote appends note to the story's notes, on a line of its own.
+func (s *UserStory) AddNote(note string) {
+	if s.Notes != "" {
+		s.Notes += "\n"
+	}
+	s.Notes += note
+}
+
+// MaxPriority returns the largest priority number in use (0 if empty).
+func (p *PRD) MaxPriority() int {
+	max := 0
+	for _, s := range p.UserStories {
+		if s.Priority > max {
+			max = s.Priority
+		}
+	}
+	return max
+}
+
+var idPattern = regexp.MustCompile(`^(.*?)(\d+)$`)
+
+// NextID returns a new story ID following the pattern of the existing ones
+// (e.g. US-007 after US-006). Defaults to US-001.
+func (p *PRD) NextID() string {
+	prefix, width, max := "US-", 3, 0
+	for _, s := range p.UserStories {
+		m := idPattern.FindStringSubmatch(s.ID)
+		if m == nil {
+			continue
+		}
+		n, _ := strconv.Atoi(m[2])
+		if n >= max {
+			prefix, width, max = m[1], len(m[2]), n
+		}
+	}
+	for n := max + 1; ; n++ {
+		id := fmt.Sprintf("%s%0*d", prefix, width, n)
+		if p.Index(id) < 0 {
+			return id
+		}
+	}
+}
+
+// Add appends a new open story with the next free ID and lowest priority.
+func (p *PRD) Add(title string) *UserStory {
+	p.UserStories = append(p.UserStories, UserStory{
+		ID:       p.NextID(),
+		Title:    title,
+		Priority: p.MaxPriority() + 1,
+	})
+	return &p.UserStories[len(p.UserStories)-1]
+}
+
+// Replace swaps the story with the given ID for s. IDs must stay unique.
+func (p *PRD) Replace(id string, s UserStory) error {
+	i := p.Index(id)
+	if i < 0 {
+		return fmt.Errorf("story %s not found", id)
+	}
+	if s.ID == "" {
+		return fmt.Errorf("story id must not be empty")
+	}
+	if s.ID != id && p.Index(s.ID) >= 0 {
+		return fmt.Errorf("story %s already exists", s.ID)
+	}
+	p.UserStories[i] = s
+	return nil
+}
+
+// Delete removes the story with the given ID.
+func (p *PRD) Delete(id string) error {
+	i := p.Index(id)
+	if i < 0 {
+		return fmt.Errorf("story %s not found", id)
+	}
+	p.UserStories = append(p.UserStories[:i], p.UserStories[i+1:]...)
+	return nil
+}
+
+// Move shifts the story with the given ID by delta positions in the list
+// and gives it the priority of the story it passes, so that the order the
+// loop picks stories in follows the list.
+func (p *PRD) Move(id string, delta int) error {
+	i := p.Index(id)
+	if i < 0 {
+		return fmt.Errorf("story %s not found", id)
+	}
+	j := i + delta
+	if j < 0 || j >= len(p.UserStories) {
+		return nil
+	}
+	moved, other := &p.UserStories[i], &p.UserStories[j]
+	if moved.Priority != other.Priority {
+		moved.Priority, other.Priority = other.Priority, moved.Priority
+	} else {
+		// Equal priorities do not order the two; make room after the
+		// one that ends up first.
+		first, second := moved, other
+		if delta > 0 {
+			first, second = other, moved
+		}
+		min := second.Priority
+		for k := range p.UserStories {
+			if s := &p.UserStories[k]; s != first && s.Priority >= min {
+				s.Priority++
+			}
+		}
+	}
+	p.UserStories[i], p.UserStories[j] = p.UserStories[j], p.UserStories[i]
+	return nil
+}
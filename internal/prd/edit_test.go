@@ -0,0 +1,41 @@
+package prd
+
+import "testing"
+
+func TestMoveChangesCurrentStory(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		priorities []int
+	}{
+		{"distinct", []int{1, 2, 3}},
+		{"equal", []int{1, 1, 1}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p := &PRD{}
+			for i, pr := range tc.priorities {
+				p.UserStories = append(p.UserStories, UserStory{ID: []string{"A", "B", "C"}[i], Priority: pr})
+			}
+			if err := p.Move("B", -1); err != nil {
+				t.Fatal(err)
+			}
+			if got := p.CurrentStory().ID; got != "B" {
+				t.Errorf("after moving B up, current story is %s", got)
+			}
+			if err := p.Move("B", 1); err != nil {
+				t.Fatal(err)
+			}
+			if err := p.Move("A", 1); err != nil {
+				t.Fatal(err)
+			}
+			var order []string
+			for len(p.UserStories) > 0 {
+				cs := p.CurrentStory()
+				order = append(order, cs.ID)
+				p.Delete(cs.ID)
+			}
+			if want := "BAC"; order[0]+order[1]+order[2] != want {
+				t.Errorf("stories run in order %v, want %s", order, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package prd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileNames are the PRD file names ralph looks for in a ralph directory, in
+// order of preference. The format is chosen by extension.
+var FileNames = []string{"prd.json", "prd.yaml", "prd.yml", "prd.md"}
+
+// Find returns the path of the PRD file in dir, or "" if there is none.
+func Find(dir string) string {
+	for _, name := range FileNames {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// IsPRDFile reports whether name is one of FileNames.
+func IsPRDFile(name string) bool {
+	for _, n := range FileNames {
+		if name == n {
+			return true
+		}
+	}
+	return false
+}
+
+// codec converts between a PRD and one on-disk format.
+type codec interface {
+	decode(data []byte) (*PRD, error)
+	// encode renders p. current is the file as it is on disk (nil if it
+	// does not exist yet); formats meant to be written by hand change as
+	// little of it as possible.
+	encode(p *PRD, current []byte) ([]byte, error)
+	// positions maps JSON pointers (/userStories/0/title) to the byte
+	// offsets they start at, for validation messages.
+	positions(data []byte) map[string]int64
+}
+
+func codecFor(path string) codec {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return yamlCodec{}
+	case ".md", ".markdown":
+		return markdownCodec{}
+	}
+	return jsonCodec{}
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) decode(data []byte) (*PRD, error) {
+	var p PRD
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (jsonCodec) positions(data []byte) map[string]int64 { return jsonPositions(data) }
+
+func (jsonCodec) encode(p *PRD, _ []byte) ([]byte, error) {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// splitLines splits data into lines without their newlines. A trailing
+// newline does not produce an empty last line.
+func SplitLines(data []byte) []string {
+	s := strings.TrimSuffix(string(data), "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+// JoinLines is the inverse of SplitLines: each line ends in a newline.
+func JoinLines(lines []string) []byte {
+	if len(lines) == 0 {
+		return nil
+	}
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
@@ -0,0 +1,422 @@
+package prd
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// markdownCodec reads and writes prd.md:
+//
+//	# Project name
+//
+//	Branch: ralph/my-feature
+//
+//	Optional description.
+//
+//	## US-001: Story title
+//
+//	Priority: 1
+//	Passes: false
+//
+//	Story description.
+//
+//	- [ ] Acceptance criterion
+//	- [ ] Another criterion
+//
+//	Notes: anything the agent wants to remember
+//
+// Each "## ID: Title" heading starts a story and checkbox items are its
+// acceptance criteria. Priority defaults to the story's position. Saving
+// rewrites only the lines of stories that changed.
+type markdownCodec struct{}
+
+var (
+	mdHeading  = regexp.MustCompile(`^(#{1,2})\s+(.*?)\s*#*\s*$`)
+	mdField    = regexp.MustCompile(`^(?i)(branch|priority|passes|notes|iterations):\s*(.*)$`)
+	mdCheckbox = regexp.MustCompile(`^\s*[-*+]\s+\[[ xX]\]\s+(.*)$`)
+	mdFence    = regexp.MustCompile("^\\s*(```|~~~)")
+)
+
+// IsFence reports whether a Markdown line opens or closes a code block.
+func IsFence(line string) bool {
+	return mdFence.MatchString(line)
+}
+
+// mdDoc is a parsed prd.md with the line ranges of its parts.
+type mdDoc struct {
+	prd      *PRD
+	preamble []string
+	sections []mdSection
+}
+
+type mdSection struct {
+	line     int // 0-based line of the heading in the file
+	lines    []string
+	heading  int            // index of the "## " line in lines
+	fields   map[string]int // lower-case field name -> line index
+	notes    []int          // line indices of Notes: lines
+	hasPrio  bool
+	position int // 1-based position, the default priority
+}
+
+// ParseError is a Markdown PRD syntax error.
+type ParseError struct {
+	Line int
+	Msg  string
+}
+
+func (e *ParseError) Error() string { return fmt.Sprintf("line %d: %s", e.Line, e.Msg) }
+
+func (markdownCodec) decode(data []byte) (*PRD, error) {
+	doc, err := parseMarkdown(data)
+	if err != nil {
+		return nil, err
+	}
+	return doc.prd, nil
+}
+
+func parseMarkdown(data []byte) (*mdDoc, error) {
+	doc := &mdDoc{prd: &PRD{UserStories: []UserStory{}}}
+	var desc []string
+	inFence := false
+	var sec *mdSection
+	var story *UserStory
+	var storyDesc []string
+
+	finishStory := func() {
+		if story == nil {
+			return
+		}
+		story.Description = joinParagraphs(storyDesc)
+		if !sec.hasPrio {
+			story.Priority = sec.position
+		}
+		doc.prd.UserStories = append(doc.prd.UserStories, *story)
+		doc.sections = append(doc.sections, *sec)
+		story, sec, storyDesc = nil, nil, nil
+	}
+
+	for n, line := range SplitLines(data) {
+		if IsFence(line) {
+			inFence = !inFence
+		}
+		if !inFence {
+			if m := mdHeading.FindStringSubmatch(line); m != nil {
+				if m[1] == "##" {
+					finishStory()
+					id, title, ok := strings.Cut(m[2], ":")
+					if !ok {
+						return nil, &ParseError{Line: n + 1, Msg: fmt.Sprintf("story heading %q must look like \"## ID: Title\"", line)}
+					}
+					story = &UserStory{ID: strings.TrimSpace(id), Title: strings.TrimSpace(title)}
+					sec = &mdSection{line: n, fields: map[string]int{}, position: len(doc.prd.UserStories) + 1}
+					sec.lines = append(sec.lines, line)
+					continue
+				}
+				if story == nil && doc.prd.Name == "" {
+					doc.prd.Name = m[2]
+					doc.preamble = append(doc.preamble, line)
+					continue
+				}
+			}
+		}
+
+		if story == nil {
+			doc.preamble = append(doc.preamble, line)
+			if m := mdField.FindStringSubmatch(line); !inFence && m != nil && strings.EqualFold(m[1], "branch") {
+				doc.prd.BranchName = strings.Trim(m[2], "` ")
+			} else if !mdHeading.MatchString(line) || inFence {
+				desc = append(desc, line)
+			}
+			continue
+		}
+
+		idx := len(sec.lines)
+		sec.lines = append(sec.lines, line)
+		if inFence {
+			storyDesc = append(storyDesc, line)
+			continue
+		}
+		if m := mdField.FindStringSubmatch(line); m != nil && !strings.EqualFold(m[1], "branch") {
+			key, val := strings.ToLower(m[1]), strings.TrimSpace(m[2])
+			switch key {
+			case "priority", "iterations":
+				v, err := strconv.Atoi(val)
+				if err != nil {
+					return nil, &ParseError{Line: n + 1, Msg: fmt.Sprintf("%s must be a number, not %q", m[1], val)}
+				}
+				if key == "priority" {
+					story.Priority, sec.hasPrio = v, true
+				} else {
+					story.Iterations = v
+				}
+			case "passes":
+				v, err := parseYesNo(val)
+				if err != nil {
+					return nil, &ParseError{Line: n + 1, Msg: err.Error()}
+				}
+				story.Passes = v
+			case "notes":
+				if story.Notes != "" {
+					story.Notes += "\n"
+				}
+				story.Notes += val
+				sec.notes = append(sec.notes, idx)
+				continue
+			}
+			sec.fields[key] = idx
+			continue
+		}
+		if m := mdCheckbox.FindStringSubmatch(line); m != nil {
+			story.AcceptanceCriteria = append(story.AcceptanceCriteria, strings.TrimSpace(m[1]))
+			continue
+		}
+		storyDesc = append(storyDesc, line)
+	}
+	finishStory()
+	doc.prd.Description = joinParagraphs(desc)
+	return doc, nil
+}
+
+func (markdownCodec) positions(data []byte) map[string]int64 {
+	// Stories are the only list; there are no unknown keys to point at.
+	pos := map[string]int64{"": 0, "/userStories": 0}
+	doc, err := parseMarkdown(data)
+	if err != nil {
+		return pos
+	}
+	for i, sec := range doc.sections {
+		at := fmt.Sprintf("/userStories/%d", i)
+		heading := offsetOf(data, sec.line+1, 1)
+		pos[at], pos[at+"/id"], pos[at+"/title"] = heading, heading, heading
+		for key, idx := range sec.fields {
+			pos[at+"/"+key] = offsetOf(data, sec.line+idx+1, 1)
+		}
+	}
+	return pos
+}
+
+func parseYesNo(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "true", "yes", "done", "x":
+		return true, nil
+	case "false", "no", "todo", "":
+		return false, nil
+	}
+	return false, fmt.Errorf("passes must be true or false, not %q", s)
+}
+
+// joinParagraphs joins lines, dropping leading and trailing blank lines.
+func joinParagraphs(lines []string) string {
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+func (markdownCodec) encode(p *PRD, current []byte) ([]byte, error) {
+	if doc, err := parseMarkdown(current); err == nil && len(current) > 0 {
+		out := patchMarkdown(doc, p)
+		if got, err := parseMarkdown(out); err == nil && samePRD(markdownView(got.prd), markdownView(p)) {
+			return out, nil
+		}
+	}
+	return renderMarkdown(p), nil
+}
+
+// markdownView drops what prd.md cannot hold, for comparisons.
+func markdownView(p *PRD) *PRD {
+	c := p.clone()
+	c.Schema, c.Metadata = "", nil
+	for i := range c.UserStories {
+		if c.UserStories[i].Priority == i+1 {
+			c.UserStories[i].Priority = 0
+		}
+	}
+	return c
+}
+
+// patchMarkdown lays out p's stories in order, reusing the original
+// section of every story that still exists and editing only the lines
+// that changed.
+func patchMarkdown(doc *mdDoc, p *PRD) []byte {
+	old := doc.prd
+	var out []string
+	if p.Name == old.Name && p.BranchName == old.BranchName && p.Description == old.Description {
+		out = append(out, doc.preamble...)
+	} else {
+		out = append(out, renderPreamble(p)...)
+	}
+
+	byID := make(map[string]int, len(old.UserStories))
+	for i, s := range old.UserStories {
+		byID[s.ID] = i
+	}
+	for i, s := range p.UserStories {
+		var lines []string
+		if j, ok := byID[s.ID]; ok {
+			lines = patchSection(doc.sections[j], old.UserStories[j], s, i+1)
+		} else {
+			lines = renderStory(s, i+1)
+		}
+		if n := len(out); n > 0 && strings.TrimSpace(out[n-1]) != "" {
+			out = append(out, "")
+		}
+		out = append(out, lines...)
+	}
+	for len(out) > 0 && strings.TrimSpace(out[len(out)-1]) == "" {
+		out = out[:len(out)-1]
+	}
+	return JoinLines(out)
+}
+
+func patchSection(sec mdSection, old, cur UserStory, position int) []string {
+	if old.Description != cur.Description || !equalStrings(old.AcceptanceCriteria, cur.AcceptanceCriteria) {
+		return renderStory(cur, position)
+	}
+	lines := append([]string(nil), sec.lines...)
+	if old.ID != cur.ID || old.Title != cur.Title {
+		lines[sec.heading] = fmt.Sprintf("## %s: %s", cur.ID, cur.Title)
+	}
+
+	// Single-line fields: rewrite in place, or add after the heading.
+	var insert []string
+	set := func(key, label, val string, zero bool) {
+		if i, ok := sec.fields[key]; ok {
+			name, _, _ := strings.Cut(lines[i], ":")
+			lines[i] = name + ": " + val
+		} else if !zero {
+			insert = append(insert, label+": "+val)
+		}
+	}
+	// Without a Priority line the story's position is its priority.
+	if sec.hasPrio && old.Priority != cur.Priority || !sec.hasPrio && cur.Priority != position {
+		set("priority", "Priority", strconv.Itoa(cur.Priority), false)
+	}
+	if old.Passes != cur.Passes {
+		set("passes", "Passes", strconv.FormatBool(cur.Passes), !cur.Passes)
+	}
+	if old.Iterations != cur.Iterations {
+		set("iterations", "Iterations", strconv.Itoa(cur.Iterations), cur.Iterations == 0)
+	}
+
+	if len(insert) > 0 {
+		at := sec.heading + 1
+		for _, i := range sec.fields {
+			if i+1 > at {
+				at = i + 1
+			}
+		}
+		if at == sec.heading+1 {
+			insert = append([]string{""}, insert...)
+		}
+		lines = append(lines[:at], append(insert, lines[at:]...)...)
+		sec.notes = append([]int(nil), sec.notes...)
+		for k, i := range sec.notes {
+			if i >= at {
+				sec.notes[k] = i + len(insert)
+			}
+		}
+	}
+
+	// Notes may span several lines; replace them as a block.
+	if old.Notes != cur.Notes {
+		notes := noteLines(cur.Notes)
+		if len(sec.notes) > 0 {
+			at := sec.notes[0]
+			drop := make(map[int]bool)
+			for _, i := range sec.notes {
+				drop[i] = true
+			}
+			var kept []string
+			for i, l := range lines {
+				if i == at {
+					kept = append(kept, notes...)
+				}
+				if !drop[i] {
+					kept = append(kept, l)
+				}
+			}
+			lines = kept
+		} else if len(notes) > 0 {
+			end := len(lines)
+			for end > 1 && strings.TrimSpace(lines[end-1]) == "" {
+				end--
+			}
+			tail := append([]string{""}, notes...)
+			lines = append(lines[:end], append(tail, lines[end:]...)...)
+		}
+	}
+	return lines
+}
+
+func noteLines(notes string) []string {
+	if notes == "" {
+		return nil
+	}
+	var lines []string
+	for _, l := range strings.Split(notes, "\n") {
+		lines = append(lines, "Notes: "+l)
+	}
+	return lines
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func renderMarkdown(p *PRD) []byte {
+	lines := renderPreamble(p)
+	for i, s := range p.UserStories {
+		lines = append(lines, "")
+		lines = append(lines, renderStory(s, i+1)...)
+	}
+	return JoinLines(lines)
+}
+
+func renderPreamble(p *PRD) []string {
+	name := p.Name
+	if name == "" {
+		name = "PRD"
+	}
+	lines := []string{"# " + name}
+	if p.BranchName != "" {
+		lines = append(lines, "", "Branch: "+p.BranchName)
+	}
+	if p.Description != "" {
+		lines = append(lines, "", p.Description)
+	}
+	return lines
+}
+
+func renderStory(s UserStory, position int) []string {
+	lines := []string{fmt.Sprintf("## %s: %s", s.ID, s.Title), ""}
+	if s.Priority != position {
+		lines = append(lines, fmt.Sprintf("Priority: %d", s.Priority))
+	}
+	lines = append(lines, fmt.Sprintf("Passes: %t", s.Passes))
+	if s.Iterations > 0 {
+		lines = append(lines, fmt.Sprintf("Iterations: %d", s.Iterations))
+	}
+	if s.Description != "" {
+		lines = append(lines, "", s.Description)
+	}
+	if len(s.AcceptanceCriteria) > 0 {
+		lines = append(lines, "")
+		for _, c := range s.AcceptanceCriteria {
+			lines = append(lines, "- [ ] "+c)
+		}
+	}
+	if notes := noteLines(s.Notes); len(notes) > 0 {
+		lines = append(lines, "")
+		lines = append(lines, notes...)
+	}
+	return lines
+}
@@ -1,54 +1,52 @@
 package prd
 
 import (
-	"encoding/json"
+	"crypto/sha256"
 	"fmt"
 	"os"
+	"path/filepath"
 	"sort"
 )
 
 type PRD struct {
-	Name        string       `json:"name"`
-	Description string       `json:"description"`
-	BranchName  string       `json:"branchName"`
-	UserStories []UserStory  `json:"userStories"`
-	Metadata    *PRDMetadata `json:"metadata,omitempty"`
+	Schema      string       `json:"$schema,omitempty" yaml:"$schema,omitempty"`
+	Name        string       `json:"name" yaml:"name,omitempty"`
+	Description string       `json:"description" yaml:"description,omitempty"`
+	BranchName  string       `json:"branchName" yaml:"branchName,omitempty"`
+	UserStories []UserStory  `json:"userStories" yaml:"userStories"`
+	Metadata    *PRDMetadata `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+
+	base *snapshot // on-disk state at load time, see Save
 }
 
 type UserStory struct {
-	ID                 string   `json:"id"`
-	Title              string   `json:"title"`
-	Description        string   `json:"description,omitempty"`
-	AcceptanceCriteria []string `json:"acceptanceCriteria,omitempty"`
-	Priority           int      `json:"priority"`
-	Passes             bool     `json:"passes"`
-	Notes              string   `json:"notes,omitempty"`
-	Iterations         int      `json:"iterations,omitempty"`
+	ID                 string   `json:"id" yaml:"id"`
+	Title              string   `json:"title" yaml:"title"`
+	Description        string   `json:"description,omitempty" yaml:"description,omitempty"`
+	AcceptanceCriteria []string `json:"acceptanceCriteria,omitempty" yaml:"acceptanceCriteria,omitempty"`
+	Priority           int      `json:"priority" yaml:"priority"`
+	Passes             bool     `json:"passes" yaml:"passes"`
+	Notes              string   `json:"notes,omitempty" yaml:"notes,omitempty"`
+	Iterations         int      `json:"iterations,omitempty" yaml:"iterations,omitempty"`
 }
 
 type PRDMetadata struct {
-	UpdatedAt string `json:"updatedAt,omitempty"`
+	UpdatedAt string `json:"updatedAt,omitempty" yaml:"updatedAt,omitempty"`
 }
 
+// Load reads a PRD file. The format is chosen by extension: .json, .yaml,
+// .yml or .md.
 func Load(path string) (*PRD, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
-		return nil, fmt.Errorf("reading prd.json: %w", err)
-	}
-	var p PRD
-	if err := json.Unmarshal(data, &p); err != nil {
-		return nil, fmt.Errorf("parsing prd.json: %w", err)
+		return nil, fmt.Errorf("reading %s: %w", filepath.Base(path), err)
 	}
-	return &p, nil
-}
-
-func (p *PRD) Save(path string) error {
-	data, err := json.MarshalIndent(p, "", "  ")
+	p, err := codecFor(path).decode(data)
 	if err != nil {
-		return fmt.Errorf("marshaling prd.json: %w", err)
+		return nil, fmt.Errorf("parsing %s: %w", filepath.Base(path), err)
 	}
-	data = append(data, '\n')
-	return os.WriteFile(path, data, 0644)
+	p.base = &snapshot{hash: sha256.Sum256(data), prd: p.clone()}
+	return p, nil
 }
 
 // CurrentStory returns the highest priority user story where passes=false.
@@ -0,0 +1,257 @@
+package prd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// snapshot records a PRD as it was on disk when loaded or last saved, so
+// Save can tell whether another writer (usually the agent) changed the
+// file in the meantime and merge instead of overwriting.
+type snapshot struct {
+	hash [32]byte
+	prd  *PRD
+}
+
+// ConflictError is returned by Save when the file changed on disk and the
+// same story (or top-level field) was changed differently on both sides.
+type ConflictError struct {
+	Path      string
+	Conflicts []string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s changed on disk with conflicting edits: %s",
+		filepath.Base(e.Path), strings.Join(e.Conflicts, ", "))
+}
+
+// Save writes the PRD atomically (write to a temp file, then rename), so
+// readers never see a half-written file. YAML and Markdown files are
+// updated in place, leaving unchanged stories as they were written.
+//
+// If the PRD was loaded from path and the file has changed since, the
+// on-disk version is merged in first: stories changed only on disk are
+// taken from disk, stories changed only here are kept, and a story changed
+// on both sides is a *ConflictError. The file is checked again right
+// before the rename; if it changed in between, the merge is redone, up to
+// saveRetries times. On success p holds the merged result.
+func (p *PRD) Save(path string) error {
+	c := codecFor(path)
+	var changed []string
+	for range saveRetries {
+		out := p
+		current, err := os.ReadFile(path)
+		if err != nil {
+			current = nil
+		}
+		if p.base != nil && current != nil && sha256.Sum256(current) != p.base.hash {
+			theirs, err := c.decode(current)
+			if err != nil {
+				return fmt.Errorf("%s changed on disk and no longer parses, not overwriting: %w", filepath.Base(path), err)
+			}
+			merged, conflicts := merge(p.base.prd, p, theirs)
+			if len(conflicts) > 0 {
+				return &ConflictError{Path: path, Conflicts: conflicts}
+			}
+			out = merged
+		}
+
+		data, err := c.encode(out, current)
+		if err != nil {
+			return fmt.Errorf("encoding %s: %w", filepath.Base(path), err)
+		}
+		err = ReplaceFile(path, current, data)
+		if errors.Is(err, ErrChanged) {
+			changed = changedStories(p.base, out)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if out != p {
+			*p = *out
+		}
+		p.base = &snapshot{hash: sha256.Sum256(data), prd: p.clone()}
+		return nil
+	}
+	return &ConflictError{Path: path, Conflicts: changed}
+}
+
+// saveRetries is how many times Save tries to get its change in while the
+// file is being changed by someone else.
+const saveRetries = 3
+
+// changedStories returns the IDs of the stories in p that differ from the
+// snapshot, or all of them without one.
+func changedStories(base *snapshot, p *PRD) []string {
+	var ids []string
+	for _, s := range p.UserStories {
+		if base == nil {
+			ids = append(ids, s.ID)
+			continue
+		}
+		if old, err := base.prd.Story(s.ID); err != nil || !reflect.DeepEqual(*old, s) {
+			ids = append(ids, s.ID)
+		}
+	}
+	return ids
+}
+
+// ErrChanged is returned by ReplaceFile when the file no longer holds
+// what the new content was made from.
+var ErrChanged = errors.New("changed on disk")
+
+// WriteFileAtomic replaces path with data via a temp file in the same
+// directory and a rename, keeping the existing file mode.
+func WriteFileAtomic(path string, data []byte) error {
+	return writeFileAtomic(path, data, nil)
+}
+
+// ReplaceFile is WriteFileAtomic for an update of old, the content path
+// held when it was read. If path holds something else by the time data is
+// ready to go in, it is left alone and the error is ErrChanged.
+func ReplaceFile(path string, old, data []byte) error {
+	return writeFileAtomic(path, data, func() error {
+		if current, err := os.ReadFile(path); err == nil && !bytes.Equal(current, old) {
+			return fmt.Errorf("%s %w", filepath.Base(path), ErrChanged)
+		}
+		return nil
+	})
+}
+
+// writeFileAtomic writes data to path; check, when set, runs right before
+// the rename and can call it off.
+func writeFileAtomic(path string, data []byte, check func() error) error {
+	mode := os.FileMode(0644)
+	if fi, err := os.Stat(path); err == nil {
+		mode = fi.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("creating temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op after a successful rename
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing temp file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("syncing temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("closing temp file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return fmt.Errorf("setting file mode: %w", err)
+	}
+	if check != nil {
+		if err := check(); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("replacing %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// clone returns a deep copy of the PRD without its snapshot.
+func (p *PRD) clone() *PRD {
+	c := *p
+	c.base = nil
+	c.UserStories = make([]UserStory, len(p.UserStories))
+	for i, s := range p.UserStories {
+		s.AcceptanceCriteria = append([]string(nil), s.AcceptanceCriteria...)
+		c.UserStories[i] = s
+	}
+	if p.Metadata != nil {
+		md := *p.Metadata
+		c.Metadata = &md
+	}
+	return &c
+}
+
+// merge three-way merges ours and theirs against their common base at
+// story granularity. Returns the merged PRD and any conflicts.
+func merge(base, ours, theirs *PRD) (*PRD, []string) {
+	var conflicts []string
+	out := ours.clone()
+
+	pick := func(field string, b, o, t any) any {
+		switch {
+		case reflect.DeepEqual(o, b):
+			return t
+		case reflect.DeepEqual(t, b), reflect.DeepEqual(o, t):
+			return o
+		default:
+			conflicts = append(conflicts, field)
+			return o
+		}
+	}
+	out.Name = pick("name", base.Name, ours.Name, theirs.Name).(string)
+	out.Description = pick("description", base.Description, ours.Description, theirs.Description).(string)
+	out.BranchName = pick("branchName", base.BranchName, ours.BranchName, theirs.BranchName).(string)
+	out.Metadata = pick("metadata", base.Metadata, ours.Metadata, theirs.Metadata).(*PRDMetadata)
+
+	baseByID := storiesByID(base)
+	theirsByID := storiesByID(theirs)
+	oursByID := storiesByID(ours)
+
+	out.UserStories = out.UserStories[:0]
+	for _, o := range ours.UserStories {
+		b, inBase := baseByID[o.ID]
+		t, inTheirs := theirsByID[o.ID]
+		switch {
+		case inBase && inTheirs:
+			out.UserStories = append(out.UserStories, pick(o.ID, b, o, t).(UserStory))
+		case inBase && !inTheirs:
+			// Deleted on disk: drop it unless we changed it.
+			if !reflect.DeepEqual(o, b) {
+				conflicts = append(conflicts, o.ID+" (deleted on disk)")
+				out.UserStories = append(out.UserStories, o)
+			}
+		case !inBase && inTheirs:
+			// Added on both sides with the same ID.
+			if !reflect.DeepEqual(o, t) {
+				conflicts = append(conflicts, o.ID+" (added on both sides)")
+			}
+			out.UserStories = append(out.UserStories, o)
+		default:
+			out.UserStories = append(out.UserStories, o)
+		}
+	}
+	for _, t := range theirs.UserStories {
+		if _, inOurs := oursByID[t.ID]; inOurs {
+			continue
+		}
+		b, inBase := baseByID[t.ID]
+		switch {
+		case !inBase:
+			// Added on disk.
+			out.UserStories = append(out.UserStories, t)
+		case !reflect.DeepEqual(t, b):
+			// We deleted it, but it was changed on disk.
+			conflicts = append(conflicts, t.ID+" (changed on disk)")
+			out.UserStories = append(out.UserStories, t)
+		}
+	}
+	return out, conflicts
+}
+
+func storiesByID(p *PRD) map[string]UserStory {
+	m := make(map[string]UserStory, len(p.UserStories))
+	for _, s := range p.UserStories {
+		m[s.ID] = s
+	}
+	return m
+}
@@ -0,0 +1,316 @@
+package prd
+
+import (
+	"bytes"
+	"cmp"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Schema is the JSON Schema for prd.json (draft 2020-12).
+//
+//go:embed prd.schema.json
+var Schema []byte
+
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	if s == SeverityWarning {
+		return "warning"
+	}
+	return "error"
+}
+
+// Issue is a single validation finding. Line and Column are 1-based and
+// point at the offending key or value; they are 0 when unknown.
+type Issue struct {
+	Severity Severity
+	Line     int
+	Column   int
+	Path     string // JSON pointer, e.g. /userStories/2/title
+	Message  string
+}
+
+func (i Issue) String() string {
+	if i.Line == 0 {
+		return fmt.Sprintf("%s: %s", i.Severity, i.Message)
+	}
+	return fmt.Sprintf("%d:%d: %s: %s", i.Line, i.Column, i.Severity, i.Message)
+}
+
+// HasErrors reports whether any issue is an error, or a warning when strict.
+func HasErrors(issues []Issue, strict bool) bool {
+	for _, i := range issues {
+		if i.Severity == SeverityError || strict {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateFile reads and validates a PRD file in any supported format.
+func ValidateFile(path string) ([]Issue, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", filepath.Base(path), err)
+	}
+	return validate(codecFor(path), data), nil
+}
+
+// Validate checks prd.json content against the schema and the rules the
+// loop relies on. Syntax errors, wrong types, missing or duplicate IDs and
+// empty titles are errors. Unknown fields, missing acceptance criteria and
+// priority collisions are warnings.
+func Validate(data []byte) []Issue {
+	return validate(jsonCodec{}, data)
+}
+
+func validate(c codec, data []byte) []Issue {
+	v := validator{data: data}
+	if len(bytes.TrimSpace(data)) == 0 {
+		v.add(SeverityError, "", "empty file")
+		return v.issues
+	}
+
+	p, err := c.decode(data)
+	if err != nil {
+		var syn *json.SyntaxError
+		var typ *json.UnmarshalTypeError
+		var md *ParseError
+		switch {
+		case errors.As(err, &syn):
+			v.addAt(SeverityError, syn.Offset, "", "invalid JSON: "+syn.Error())
+		case errors.As(err, &typ):
+			v.addAt(SeverityError, typ.Offset, "", fmt.Sprintf("%s must be %s, not %s", typ.Field, typeName(typ.Type), typ.Value))
+		case errors.As(err, &md):
+			v.addAt(SeverityError, offsetOf(data, md.Line, 1), "", md.Msg)
+		default:
+			v.add(SeverityError, "", err.Error())
+		}
+		return v.issues
+	}
+
+	v.pos = c.positions(data)
+	v.unknownFields("", reflect.TypeOf(PRD{}))
+	v.unknownFields("/metadata", reflect.TypeOf(PRDMetadata{}))
+
+	if _, ok := v.pos["/userStories"]; !ok {
+		v.add(SeverityError, "", "missing userStories")
+	}
+	if p.BranchName == "" {
+		v.add(SeverityWarning, "/branchName", "branchName is empty")
+	}
+
+	ids := make(map[string]int)
+	priorities := make(map[int]string)
+	for i, s := range p.UserStories {
+		at := fmt.Sprintf("/userStories/%d", i)
+		v.unknownFields(at, reflect.TypeOf(UserStory{}))
+
+		label := s.ID
+		if label == "" {
+			label = fmt.Sprintf("story #%d", i+1)
+			v.add(SeverityError, at, label+" has no id")
+		} else if first, dup := ids[s.ID]; dup {
+			v.add(SeverityError, at+"/id", fmt.Sprintf("duplicate id %s (first used by story #%d)", s.ID, first+1))
+			label = fmt.Sprintf("%s (story #%d)", s.ID, i+1)
+		} else {
+			ids[s.ID] = i
+		}
+
+		if strings.TrimSpace(s.Title) == "" {
+			v.add(SeverityError, at+"/title", label+" has an empty title")
+		}
+		if len(s.AcceptanceCriteria) == 0 {
+			v.add(SeverityWarning, at, label+" has no acceptance criteria")
+		}
+		if other, taken := priorities[s.Priority]; taken {
+			v.add(SeverityWarning, at+"/priority", fmt.Sprintf("%s has the same priority (%d) as %s", label, s.Priority, other))
+		} else {
+			priorities[s.Priority] = label
+		}
+	}
+
+	slices.SortStableFunc(v.issues, func(a, b Issue) int {
+		return cmp.Or(cmp.Compare(a.Line, b.Line), cmp.Compare(a.Column, b.Column))
+	})
+	return v.issues
+}
+
+type validator struct {
+	data   []byte
+	pos    map[string]int64
+	issues []Issue
+}
+
+// add records an issue at the position of path, or of the closest parent
+// that has one.
+func (v *validator) add(sev Severity, path, msg string) {
+	for p := path; ; p = p[:strings.LastIndex(p, "/")] {
+		if off, ok := v.pos[p]; ok {
+			v.addAt(sev, off, path, msg)
+			return
+		}
+		if p == "" {
+			break
+		}
+	}
+	v.issues = append(v.issues, Issue{Severity: sev, Path: path, Message: msg})
+}
+
+func (v *validator) addAt(sev Severity, offset int64, path, msg string) {
+	line, col := lineCol(v.data, offset)
+	v.issues = append(v.issues, Issue{Severity: sev, Line: line, Column: col, Path: path, Message: msg})
+}
+
+// unknownFields warns about keys of the object at path that t does not
+// declare.
+func (v *validator) unknownFields(path string, t reflect.Type) {
+	known := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			known[name] = true
+		}
+	}
+	for p := range v.pos {
+		parent, key, ok := cutLast(p)
+		if !ok || parent != path || known[key] {
+			continue
+		}
+		v.add(SeverityWarning, p, fmt.Sprintf("unknown field %q", key))
+	}
+}
+
+// jsonPositions maps the JSON pointer of every object member and array
+// element to the byte offset where its key (or, for array elements, its
+// value) starts. The root is "".
+func jsonPositions(data []byte) map[string]int64 {
+	pos := map[string]int64{"": skipSpace(data, 0)}
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	type frame struct {
+		path  string
+		array bool
+		index int
+		key   bool // next string token in an object is a key
+	}
+	var stack []frame
+	var pending string // path of the member whose value comes next
+
+	for {
+		start := skipSpace(data, dec.InputOffset())
+		tok, err := dec.Token()
+		if err != nil {
+			return pos
+		}
+
+		path := pending
+		if n := len(stack); n > 0 {
+			top := &stack[n-1]
+			if top.array {
+				if d, ok := tok.(json.Delim); !ok || d != ']' {
+					path = fmt.Sprintf("%s/%d", top.path, top.index)
+					pos[path] = start
+					top.index++
+				}
+			} else if top.key {
+				if k, ok := tok.(string); ok {
+					pending = top.path + "/" + escapePointer(k)
+					pos[pending] = start
+					top.key = false
+					continue
+				}
+			}
+		}
+
+		switch tok {
+		case json.Delim('{'):
+			stack = append(stack, frame{path: path, key: true})
+		case json.Delim('['):
+			stack = append(stack, frame{path: path, array: true})
+		case json.Delim('}'), json.Delim(']'):
+			stack = stack[:len(stack)-1]
+		}
+		if n := len(stack); n > 0 && !stack[n-1].array {
+			stack[n-1].key = true
+		}
+	}
+}
+
+// skipSpace returns the offset of the next token at or after off, skipping
+// whitespace and the separators json.Decoder consumes lazily.
+func skipSpace(data []byte, off int64) int64 {
+	for off < int64(len(data)) {
+		switch data[off] {
+		case ' ', '\t', '\r', '\n', ',', ':':
+			off++
+		default:
+			return off
+		}
+	}
+	return off
+}
+
+// offsetOf converts a 1-based line and column to a byte offset.
+func offsetOf(data []byte, line, col int) int64 {
+	off := 0
+	for ; line > 1; line-- {
+		i := bytes.IndexByte(data[off:], '\n')
+		if i < 0 {
+			return int64(len(data))
+		}
+		off += i + 1
+	}
+	return int64(min(off+col-1, len(data)))
+}
+
+func lineCol(data []byte, offset int64) (int, int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	col := int(offset) - bytes.LastIndexByte(before, '\n')
+	return line, col
+}
+
+func cutLast(path string) (parent, key string, ok bool) {
+	i := strings.LastIndex(path, "/")
+	if i < 0 {
+		return "", "", false
+	}
+	return path[:i], strings.NewReplacer("~1", "/", "~0", "~").Replace(path[i+1:]), true
+}
+
+func escapePointer(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
+
+func typeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int64:
+		return "an integer"
+	case reflect.Slice:
+		return "an array"
+	case reflect.Struct, reflect.Pointer:
+		return "an object"
+	}
+	return t.String()
+}
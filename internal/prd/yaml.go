@@ -0,0 +1,464 @@
+package prd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlCodec reads and writes prd.yaml. Saving patches only the lines of
+// the keys and stories that changed, so comments and formatting elsewhere
+// survive. Files it cannot patch (flow style, missing or repeated story
+// IDs) are re-rendered as a whole.
+type yamlCodec struct{}
+
+func (yamlCodec) decode(data []byte) (*PRD, error) {
+	var p PRD
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (yamlCodec) encode(p *PRD, current []byte) ([]byte, error) {
+	if len(bytes.TrimSpace(current)) > 0 {
+		if out, ok := patchYAML(p, current); ok {
+			return out, nil
+		}
+	}
+	return marshalYAML(p)
+}
+
+func (yamlCodec) positions(data []byte) map[string]int64 {
+	pos := map[string]int64{"": 0}
+	var doc yaml.Node
+	if yaml.Unmarshal(data, &doc) != nil || len(doc.Content) == 0 {
+		return pos
+	}
+	var walk func(n *yaml.Node, path string)
+	walk = func(n *yaml.Node, path string) {
+		switch n.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				k := n.Content[i]
+				p := path + "/" + escapePointer(k.Value)
+				pos[p] = offsetOf(data, k.Line, k.Column)
+				walk(n.Content[i+1], p)
+			}
+		case yaml.SequenceNode:
+			for i, item := range n.Content {
+				p := fmt.Sprintf("%s/%d", path, i)
+				pos[p] = offsetOf(data, item.Line, item.Column)
+				walk(item, p)
+			}
+		}
+	}
+	walk(doc.Content[0], "")
+	return pos
+}
+
+func marshalYAML(v any) ([]byte, error) {
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// patchYAML rewrites current so it decodes to p. ok is false if the change
+// cannot be expressed as line edits.
+func patchYAML(p *PRD, current []byte) (out []byte, ok bool) {
+	var doc, want yaml.Node
+	if yaml.Unmarshal(current, &doc) != nil || len(doc.Content) == 0 {
+		return nil, false
+	}
+	if want.Encode(p) != nil {
+		return nil, false
+	}
+	root, wantRoot := doc.Content[0], &want
+	if want.Kind == yaml.DocumentNode {
+		wantRoot = want.Content[0]
+	}
+	if root.Kind != yaml.MappingNode || root.Style&yaml.FlowStyle != 0 {
+		return nil, false
+	}
+
+	e := &yamlEditor{lines: SplitLines(current)}
+	if !e.patchStories(root, wantRoot) {
+		return nil, false
+	}
+	if !e.patchMapping(root, wantRoot, len(e.lines), reflect.TypeOf(PRD{}), "userStories") {
+		return nil, false
+	}
+	out = JoinLines(e.apply())
+
+	// Belt and braces: only keep the patch if it means exactly p.
+	got, err := yamlCodec{}.decode(out)
+	if err != nil || !samePRD(got, p) {
+		return nil, false
+	}
+	return out, true
+}
+
+// samePRD compares two PRDs by their JSON encoding, which ignores the
+// difference between nil and empty slices.
+func samePRD(a, b *PRD) bool {
+	ja, err1 := json.Marshal(a)
+	jb, err2 := json.Marshal(b)
+	return err1 == nil && err2 == nil && bytes.Equal(ja, jb)
+}
+
+// yamlEdit replaces lines[start:end] with lines; start == end inserts.
+type yamlEdit struct {
+	start, end int
+	lines      []string
+}
+
+// yamlEditor collects line edits against lines, which start at line index
+// base of the file (node positions are always file-relative).
+type yamlEditor struct {
+	lines []string
+	base  int
+	edits []yamlEdit
+}
+
+func (e *yamlEditor) line(i int) string { return e.lines[i-e.base] }
+
+func (e *yamlEditor) edit(start, end int, lines []string) {
+	e.edits = append(e.edits, yamlEdit{start, end, lines})
+}
+
+// apply performs the edits back to front and returns the resulting lines.
+// Insertions at the same line keep the order they were made in.
+func (e *yamlEditor) apply() []string {
+	order := make([]int, len(e.edits))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		ea, eb := e.edits[order[a]], e.edits[order[b]]
+		if ea.start != eb.start {
+			return ea.start > eb.start
+		}
+		return order[a] > order[b]
+	})
+	lines := append([]string(nil), e.lines...)
+	for _, i := range order {
+		ed := e.edits[i]
+		start, end := ed.start-e.base, ed.end-e.base
+		lines = append(lines[:start:start], append(append([]string(nil), ed.lines...), lines[end:]...)...)
+	}
+	return lines
+}
+
+// trimEnd moves end back over trailing blank lines and over comment lines
+// indented no deeper than indent, which belong to whatever follows.
+func (e *yamlEditor) trimEnd(start, end, indent int) int {
+	for end > start+1 {
+		l := e.line(end - 1)
+		t := strings.TrimSpace(l)
+		if t == "" || strings.HasPrefix(t, "#") && len(l)-len(strings.TrimLeft(l, " ")) <= indent {
+			end--
+			continue
+		}
+		break
+	}
+	return end
+}
+
+// patchStories rewrites the userStories sequence: stories are matched by
+// ID, edited in place, laid out in the new order with the comments above
+// them, and new stories are rendered at their position.
+func (e *yamlEditor) patchStories(root, want *yaml.Node) bool {
+	_, oldSeq, _, oldEnd := mappingEntry(root, "userStories", len(e.lines))
+	_, wantSeq, _, _ := mappingEntry(want, "userStories", 0)
+	if oldSeq == nil || wantSeq == nil {
+		return oldSeq == nil && wantSeq == nil
+	}
+	if sameValue(oldSeq, wantSeq, reflect.TypeOf([]UserStory(nil))) {
+		return true
+	}
+	if oldSeq.Style&yaml.FlowStyle != 0 || len(oldSeq.Content) == 0 {
+		return false
+	}
+	oldIDs, okOld := storyIDs(oldSeq)
+	wantIDs, okWant := storyIDs(wantSeq)
+	if !okOld || !okWant {
+		return false
+	}
+
+	first := oldSeq.Content[0]
+	dash := strings.LastIndex(e.line(first.Line - 1)[:first.Column-1], "-")
+	if dash < 0 {
+		return false
+	}
+
+	// Each story's block runs from the comments above it to the next
+	// block; blank lines between stories are re-added when joining.
+	starts := make([]int, len(oldSeq.Content))
+	for i, item := range oldSeq.Content {
+		if item.Kind != yaml.MappingNode || item.Style&yaml.FlowStyle != 0 {
+			return false
+		}
+		start := item.Line - 1
+		floor := oldSeq.Line
+		if i > 0 {
+			floor = oldSeq.Content[i-1].Line
+		}
+		for start > floor && strings.HasPrefix(strings.TrimSpace(e.line(start-1)), "#") {
+			start--
+		}
+		starts[i] = start
+	}
+	seqEnd := e.trimEnd(starts[len(starts)-1], oldEnd, dash)
+	spaced := false
+
+	blocks := make(map[string][]string, len(oldIDs))
+	for i, item := range oldSeq.Content {
+		end := seqEnd
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		blockEnd := end
+		for blockEnd > starts[i]+1 && strings.TrimSpace(e.line(blockEnd-1)) == "" {
+			blockEnd--
+		}
+		if i == 0 && blockEnd < end {
+			spaced = true
+		}
+		sub := &yamlEditor{lines: e.lines[starts[i]-e.base : blockEnd-e.base], base: starts[i]}
+		j := slices.Index(wantIDs, oldIDs[i])
+		if j < 0 {
+			continue
+		}
+		if !sub.patchMapping(item, wantSeq.Content[j], blockEnd, reflect.TypeOf(UserStory{}), "") {
+			return false
+		}
+		blocks[oldIDs[i]] = sub.apply()
+	}
+
+	var out []string
+	pad := strings.Repeat(" ", dash)
+	for i, item := range wantSeq.Content {
+		block, ok := blocks[wantIDs[i]]
+		if !ok {
+			seq := &yaml.Node{Kind: yaml.SequenceNode, Content: []*yaml.Node{item}}
+			rendered, err := marshalYAML(seq)
+			if err != nil {
+				return false
+			}
+			block = indent(SplitLines(rendered), pad, pad)
+		}
+		if i > 0 && spaced {
+			out = append(out, "")
+		}
+		out = append(out, block...)
+	}
+	e.edit(starts[0], seqEnd, out)
+	return true
+}
+
+// patchMapping edits the block mapping m, whose last line is end-1, so that
+// it matches want key by key. m decodes into struct type typ; keys that are
+// not its fields are left alone, and skip names a key handled elsewhere.
+// Values are compared as the field decodes them, so "passes: no" is left
+// as written when passes stays false.
+func (e *yamlEditor) patchMapping(m, want *yaml.Node, end int, typ reflect.Type, skip string) bool {
+	if m.Style&yaml.FlowStyle != 0 {
+		return sameValue(m, want, typ)
+	}
+	fields := yamlFields(typ)
+	oldKeys := make(map[string]bool)
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		key, val := m.Content[i], m.Content[i+1]
+		name := key.Value
+		oldKeys[name] = true
+		ft, known := fields[name]
+		if name == skip || !known {
+			continue
+		}
+		start := key.Line - 1
+		keyEnd := end
+		if i+2 < len(m.Content) {
+			keyEnd = m.Content[i+2].Line - 1
+		}
+		_, wantVal, _, _ := mappingEntry(want, name, 0)
+		switch {
+		case wantVal == nil && isZeroAs(val, ft):
+		case wantVal == nil:
+			// Dropped (an omitempty field was cleared). Removing the
+			// first key of a sequence item would take the dash with it.
+			if strings.Contains(e.line(start)[:key.Column-1], "-") {
+				return false
+			}
+			e.edit(start, e.trimEnd(start, keyEnd, key.Column-1), nil)
+		case sameValue(val, wantVal, ft):
+		default:
+			rendered, err := renderPair(name, wantVal)
+			if err != nil {
+				return false
+			}
+			if len(rendered) == 1 && e.isInline(key, val, keyEnd) {
+				e.replaceValue(start, val, rendered[0])
+				continue
+			}
+			line := e.line(start)
+			e.edit(start, e.trimEnd(start, keyEnd, key.Column-1),
+				indent(rendered, line[:key.Column-1], strings.Repeat(" ", key.Column-1)))
+		}
+	}
+
+	// Keys that are new go after the last existing one.
+	if len(m.Content) == 0 {
+		return false
+	}
+	pad := strings.Repeat(" ", m.Content[0].Column-1)
+	lastKey := m.Content[len(m.Content)-2]
+	at := e.trimEnd(lastKey.Line-1, end, lastKey.Column-1)
+	for i := 0; i+1 < len(want.Content); i += 2 {
+		name := want.Content[i].Value
+		ft, known := fields[name]
+		if oldKeys[name] || name == skip || !known || isZeroAs(want.Content[i+1], ft) {
+			// A missing key already decodes to the zero value.
+			continue
+		}
+		rendered, err := renderPair(name, want.Content[i+1])
+		if err != nil {
+			return false
+		}
+		e.edit(at, at, indent(rendered, pad, pad))
+	}
+	return true
+}
+
+// replaceValue swaps the value on a "key: value" line for the one in the
+// rendered "key: value" line, keeping any trailing comment.
+func (e *yamlEditor) replaceValue(lineIdx int, val *yaml.Node, rendered string) {
+	line := e.line(lineIdx)
+	_, newVal, _ := strings.Cut(rendered, ": ")
+	valEnd := len(line)
+	if c := val.LineComment; c != "" {
+		if i := strings.LastIndex(line, c); i >= val.Column-1 {
+			valEnd = len(strings.TrimRight(line[:i], " \t"))
+		}
+	}
+	e.edit(lineIdx, lineIdx+1, []string{line[:val.Column-1] + newVal + line[valEnd:]})
+}
+
+// isInline reports whether val is a scalar written entirely on its key's
+// line, i.e. nothing but blank lines and comments follow before keyEnd.
+func (e *yamlEditor) isInline(key, val *yaml.Node, keyEnd int) bool {
+	return val.Kind == yaml.ScalarNode && !isBlockScalar(val) &&
+		val.Line == key.Line && e.trimEnd(key.Line-1, keyEnd, key.Column-1) == key.Line
+}
+
+func isBlockScalar(n *yaml.Node) bool {
+	return n.Kind == yaml.ScalarNode && n.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0
+}
+
+// renderPair renders "name: value" as block YAML lines.
+func renderPair(name string, val *yaml.Node) ([]string, error) {
+	m := &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+		{Kind: yaml.ScalarNode, Value: name},
+		val,
+	}}
+	data, err := marshalYAML(m)
+	if err != nil {
+		return nil, err
+	}
+	return SplitLines(data), nil
+}
+
+// indent prefixes the first line with first and the others with rest.
+func indent(lines []string, first, rest string) []string {
+	out := make([]string, len(lines))
+	for i, l := range lines {
+		if i == 0 {
+			out[i] = first + l
+		} else if l != "" {
+			out[i] = rest + l
+		}
+	}
+	return out
+}
+
+// mappingEntry finds key in mapping m and returns the key and value nodes
+// and the line range [start, end) the entry spans; end is the entry's last
+// line + 1 given the mapping ends before line mappingEnd.
+func mappingEntry(m *yaml.Node, key string, mappingEnd int) (k, v *yaml.Node, start, end int) {
+	if m.Kind == yaml.DocumentNode && len(m.Content) > 0 {
+		m = m.Content[0]
+	}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value != key {
+			continue
+		}
+		end = mappingEnd
+		if i+2 < len(m.Content) {
+			end = m.Content[i+2].Line - 1
+		}
+		return m.Content[i], m.Content[i+1], m.Content[i].Line - 1, end
+	}
+	return nil, nil, 0, 0
+}
+
+func storyID(item *yaml.Node) string {
+	_, v, _, _ := mappingEntry(item, "id", 0)
+	if v == nil {
+		return ""
+	}
+	return v.Value
+}
+
+// storyIDs returns the IDs of the stories in seq. ok is false if an ID is
+// missing or repeated, since stories cannot be matched up then.
+func storyIDs(seq *yaml.Node) (ids []string, ok bool) {
+	seen := make(map[string]bool)
+	for _, item := range seq.Content {
+		id := storyID(item)
+		if id == "" || seen[id] {
+			return nil, false
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	return ids, true
+}
+
+// yamlFields returns the YAML keys of the fields of struct type t, with
+// the field types.
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		if name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ","); name != "" && name != "-" {
+			fields[name] = t.Field(i).Type
+		}
+	}
+	return fields
+}
+
+// isZeroAs reports whether n decodes to the zero value of type t.
+func isZeroAs(n *yaml.Node, t reflect.Type) bool {
+	v := reflect.New(t)
+	return n.Decode(v.Interface()) == nil && v.Elem().IsZero()
+}
+
+// sameValue compares two nodes by the values of type t they decode to.
+func sameValue(a, b *yaml.Node, t reflect.Type) bool {
+	va, vb := reflect.New(t), reflect.New(t)
+	if a.Decode(va.Interface()) != nil || b.Decode(vb.Interface()) != nil {
+		return false
+	}
+	return reflect.DeepEqual(va.Interface(), vb.Interface())
+}
@@ -0,0 +1,43 @@
+package prd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestYAMLSaveKeepsUnchangedValues(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prd.yaml")
+	in := `name: Demo
+userStories:
+  - id: US-001
+    title: First
+    priority: 1
+    passes: yes # done by hand
+  - id: US-002
+    title: Second
+    priority: 0x2
+    passes: no
+`
+	if err := os.WriteFile(path, []byte(in), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	p, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := p.Story("US-002")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.AddNote("Needs a migration")
+	if err := p.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(path)
+	want := in + "    notes: Needs a migration\n"
+	if string(data) != want {
+		t.Errorf("saved:\n%s\nwant:\n%s", data, want)
+	}
+}
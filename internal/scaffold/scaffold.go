@@ -0,0 +1,102 @@
+// Package scaffold writes the files a new ralph project starts from:
+// prompt files for the agents, a sample PRD, progress.txt and the project
+// config. The templates are embedded, so `ralph init` works offline and
+// always writes the version that matches the binary.
+package scaffold
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/template"
+	"time"
+
+	"github.com/zhrkvl/ralph-go/internal/config"
+)
+
+// Version identifies the template set; it is written into the prompt
+// files and the config so a scaffolded project can be traced back to it.
+const Version = 1
+
+//go:embed templates
+var templates embed.FS
+
+// Options describes the project to scaffold.
+type Options struct {
+	RalphDir   string   // where the prompt files, PRD and progress.txt go
+	ProjectDir string   // where .ralph-tui/config.toml goes
+	Project    string   // project name for the PRD and prompts
+	Agent      string   // default agent written to config.toml
+	Branch     string   // branchName of the sample PRD
+	Gates      []string // quality check commands the agent must pass
+}
+
+// File is a file Init writes.
+type File struct {
+	Path     string
+	template string
+}
+
+// Files lists the files Init writes for opts, in order.
+func Files(opts Options) []File {
+	return []File{
+		{filepath.Join(opts.RalphDir, "CLAUDE.md"), "prompt.md"},
+		{filepath.Join(opts.RalphDir, "prompt.md"), "prompt.md"},
+		{filepath.Join(opts.RalphDir, "prd.json"), "prd.json"},
+		{filepath.Join(opts.RalphDir, "progress.txt"), "progress.txt"},
+		{config.ProjectPath(opts.ProjectDir), "config.toml"},
+	}
+}
+
+// Existing returns the files of Files(opts) that already exist.
+func Existing(opts Options) []string {
+	var paths []string
+	for _, f := range Files(opts) {
+		if _, err := os.Stat(f.Path); err == nil {
+			paths = append(paths, f.Path)
+		}
+	}
+	return paths
+}
+
+// Init renders the templates and writes them, overwriting existing files.
+// Callers check Existing first.
+func Init(opts Options) ([]string, error) {
+	data := map[string]any{
+		"Version":       Version,
+		"ConfigVersion": config.Version,
+		"Project":       opts.Project,
+		"Agent":         opts.Agent,
+		"Branch":        opts.Branch,
+		"Gates":         opts.Gates,
+		"PRD":           "prd.json",
+		"Started":       time.Now().Format(time.UnixDate),
+	}
+	funcs := template.FuncMap{"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	}}
+
+	var written []string
+	for _, f := range Files(opts) {
+		tmpl, err := template.New(f.template).Funcs(funcs).ParseFS(templates, "templates/"+f.template)
+		if err != nil {
+			return written, err
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return written, fmt.Errorf("rendering %s: %w", f.template, err)
+		}
+		if err := os.MkdirAll(filepath.Dir(f.Path), 0o755); err != nil {
+			return written, err
+		}
+		if err := os.WriteFile(f.Path, buf.Bytes(), 0o644); err != nil {
+			return written, err
+		}
+		written = append(written, f.Path)
+	}
+	return written, nil
+}
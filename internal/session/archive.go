@@ -37,10 +37,11 @@ func CheckAndArchive(ralphDir string, p *prd.PRD) (bool, error) {
 		return false, fmt.Errorf("creating archive folder: %w", err)
 	}
 
-	// Copy prd.json
-	prdPath := filepath.Join(ralphDir, "prd.json")
-	if data, err := os.ReadFile(prdPath); err == nil {
-		os.WriteFile(filepath.Join(archiveFolder, "prd.json"), data, 0644)
+	// Copy the PRD file
+	if prdPath := prd.Find(ralphDir); prdPath != "" {
+		if data, err := os.ReadFile(prdPath); err == nil {
+			os.WriteFile(filepath.Join(archiveFolder, filepath.Base(prdPath)), data, 0644)
+		}
 	}
 
 	// Copy progress.txt
@@ -78,12 +79,28 @@ func resetProgressFile(ralphDir string) error {
 	return os.WriteFile(path, []byte(content), 0644)
 }
 
+// AppendGuidance appends a human guidance note to progress.txt, under a
+// "Human guidance" heading so the agent finds it when reading the log.
+func AppendGuidance(ralphDir, note string) error {
+	path := filepath.Join(ralphDir, "progress.txt")
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	entry := fmt.Sprintf("\n## Human guidance (%s)\n- %s\n---\n",
+		time.Now().Format(time.UnixDate), strings.ReplaceAll(note, "\n", "\n  "))
+	_, err = f.WriteString(entry)
+	return err
+}
+
 // ArchiveEntry represents an archived session found in the archive/ directory.
 type ArchiveEntry struct {
 	Path       string
 	Date       string
 	BranchName string
 	HasPRD     bool
+	PRDFile    string // base name of the archived PRD, e.g. prd.yaml
 	HasProgress bool
 }
 
@@ -116,14 +133,15 @@ func ListArchives(ralphDir string) ([]ArchiveEntry, error) {
 		}
 
 		path := filepath.Join(archiveDir, name)
-		_, prdErr := os.Stat(filepath.Join(path, "prd.json"))
+		prdPath := prd.Find(path)
 		_, progErr := os.Stat(filepath.Join(path, "progress.txt"))
 
 		archives = append(archives, ArchiveEntry{
 			Path:        path,
 			Date:        date,
 			BranchName:  branch,
-			HasPRD:      prdErr == nil,
+			HasPRD:      prdPath != "",
+			PRDFile:     filepath.Base(prdPath),
 			HasProgress: progErr == nil,
 		})
 	}
@@ -1,12 +1,15 @@
 package session
 
 import (
+	"bufio"
 	"crypto/rand"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -17,9 +20,26 @@ type IterationLog struct {
 	Agent      string
 	StartedAt  time.Time
 	file       *os.File
+	rawFile    *os.File
+	rawMu      sync.Mutex
 	hash       string
 }
 
+// RawHeader is the first line of a .raw.jsonl stream file.
+type RawHeader struct {
+	Agent     string    `json:"agent"`
+	TaskID    string    `json:"taskId"`
+	StartedAt time.Time `json:"startedAt"`
+}
+
+// RawRecord is a single raw output line with its offset from the start
+// of the iteration.
+type RawRecord struct {
+	OffsetMs int64  `json:"t"`
+	Stream   string `json:"stream"`
+	Line     string `json:"line"`
+}
+
 func NewIterationLog(projectDir, taskID, taskTitle, agent string) (*IterationLog, error) {
 	dir := filepath.Join(projectDir, ".ralph-tui", "iterations")
 	if err := os.MkdirAll(dir, 0755); err != nil {
@@ -39,6 +59,15 @@ func NewIterationLog(projectDir, taskID, taskTitle, agent string) (*IterationLog
 		return nil, err
 	}
 
+	rawName := RawStreamPath(filename)
+	rawFile, err := os.Create(filepath.Join(dir, rawName))
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	header, _ := json.Marshal(RawHeader{Agent: agent, TaskID: taskID, StartedAt: now.UTC()})
+	rawFile.Write(append(header, '\n'))
+
 	log := &IterationLog{
 		ProjectDir: projectDir,
 		TaskID:     taskID,
@@ -46,6 +75,7 @@ func NewIterationLog(projectDir, taskID, taskTitle, agent string) (*IterationLog
 		Agent:      agent,
 		StartedAt:  now,
 		file:       f,
+		rawFile:    rawFile,
 		hash:       hash,
 	}
 
@@ -57,6 +87,7 @@ func NewIterationLog(projectDir, taskID, taskTitle, agent string) (*IterationLog
 	sb.WriteString(fmt.Sprintf("- **Task Title**: %s\n", taskTitle))
 	sb.WriteString(fmt.Sprintf("- **Started At**: %s\n", now.UTC().Format(time.RFC3339)))
 	sb.WriteString(fmt.Sprintf("- **Agent**: %s\n", agent))
+	sb.WriteString(fmt.Sprintf("- **Raw Stream**: %s\n", rawName))
 	sb.WriteString("--- RAW OUTPUT ---\n\n")
 	f.WriteString(sb.String())
 
@@ -69,7 +100,33 @@ func (l *IterationLog) WriteLine(line string) {
 	}
 }
 
+// RecordRaw appends an unparsed agent output line to the raw stream file.
+// Safe for concurrent use by the stdout and stderr readers.
+func (l *IterationLog) RecordRaw(stream, line string) {
+	l.rawMu.Lock()
+	defer l.rawMu.Unlock()
+	if l.rawFile == nil {
+		return
+	}
+	data, err := json.Marshal(RawRecord{
+		OffsetMs: time.Since(l.StartedAt).Milliseconds(),
+		Stream:   stream,
+		Line:     line,
+	})
+	if err != nil {
+		return
+	}
+	l.rawFile.Write(append(data, '\n'))
+}
+
 func (l *IterationLog) Close(completed bool, promiseDetected bool) error {
+	l.rawMu.Lock()
+	if l.rawFile != nil {
+		l.rawFile.Close()
+		l.rawFile = nil
+	}
+	l.rawMu.Unlock()
+
 	if l.file == nil {
 		return nil
 	}
@@ -87,16 +144,63 @@ func (l *IterationLog) Close(completed bool, promiseDetected bool) error {
 	sb.WriteString(fmt.Sprintf("- **Task Completed**: %v\n", completed))
 	sb.WriteString(fmt.Sprintf("- **Promise Detected**: %v\n", promiseDetected))
 	sb.WriteString(fmt.Sprintf("- **Ended At**: %s\n", time.Now().UTC().Format(time.RFC3339)))
-	sb.WriteString(fmt.Sprintf("- **Duration**: %s\n", formatDuration(duration)))
+	sb.WriteString(fmt.Sprintf("- **Duration**: %s\n", FormatDuration(duration)))
 
 	l.file.WriteString(sb.String())
 	return l.file.Close()
 }
 
-func formatDuration(d time.Duration) string {
+// RawStreamPath returns the raw stream file that belongs to an iteration
+// log. Paths that already point at a raw stream are returned unchanged.
+func RawStreamPath(logPath string) string {
+	if strings.HasSuffix(logPath, ".raw.jsonl") {
+		return logPath
+	}
+	return strings.TrimSuffix(logPath, ".log") + ".raw.jsonl"
+}
+
+// ReadRawStream loads a recorded raw stream for replay. path may be either
+// the iteration .log or its .raw.jsonl companion.
+func ReadRawStream(path string) (*RawHeader, []RawRecord, error) {
+	f, err := os.Open(RawStreamPath(path))
+	if err != nil {
+		return nil, nil, fmt.Errorf("opening raw stream: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1024*1024), 4*1024*1024)
+
+	if !scanner.Scan() {
+		return nil, nil, fmt.Errorf("raw stream %s is empty", f.Name())
+	}
+	var header RawHeader
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		return nil, nil, fmt.Errorf("parsing raw stream header: %w", err)
+	}
+
+	var records []RawRecord
+	for n := 2; scanner.Scan(); n++ {
+		var rec RawRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, nil, fmt.Errorf("parsing raw stream line %d: %w", n, err)
+		}
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("reading raw stream: %w", err)
+	}
+	return &header, records, nil
+}
+
+// FormatDuration formats d as e.g. "45s", "3m 20s" or "2h 5m".
+func FormatDuration(d time.Duration) string {
 	if d < time.Minute {
 		return fmt.Sprintf("%ds", int(d.Seconds()))
 	}
+	if d >= time.Hour {
+		return fmt.Sprintf("%dh %dm", int(d.Hours()), int(d.Minutes())%60)
+	}
 	m := int(d.Minutes())
 	s := int(d.Seconds()) % 60
 	return fmt.Sprintf("%dm %ds", m, s)
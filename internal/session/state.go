@@ -14,7 +14,7 @@ import (
 type Session struct {
 	Version          int           `json:"version"`
 	SessionID        string        `json:"sessionId"`
-	Status           string        `json:"status"` // running, completed, failed, interrupted
+	Status           string        `json:"status"` // running, blocked, completed, failed, interrupted
 	StartedAt        time.Time     `json:"startedAt"`
 	UpdatedAt        time.Time     `json:"updatedAt"`
 	CurrentIteration int           `json:"currentIteration"`
@@ -22,10 +22,14 @@ type Session struct {
 	TasksCompleted   int           `json:"tasksCompleted"`
 	IsPaused         bool          `json:"isPaused"`
 	AgentPlugin      string        `json:"agentPlugin"`
+	Profile          string        `json:"profile,omitempty"` // config profile the session was started with
+	Server           string        `json:"server,omitempty"`  // address of the HTTP API, see package api
+	CostUSD          float64       `json:"costUsd,omitempty"` // total reported by the agent
 	TrackerState     *TrackerState `json:"trackerState"`
 	Iterations       []any         `json:"iterations"`
 	CWD              string        `json:"cwd"`
 	ActiveTaskIDs    []string      `json:"activeTaskIds"`
+	PendingGuidance  []string      `json:"pendingGuidance,omitempty"`
 }
 
 type TrackerState struct {
@@ -48,6 +52,9 @@ type SessionMeta struct {
 	StartedAt        time.Time  `json:"startedAt"`
 	UpdatedAt        time.Time  `json:"updatedAt"`
 	AgentPlugin      string     `json:"agentPlugin"`
+	Profile          string     `json:"profile,omitempty"`
+	Server           string     `json:"server,omitempty"`
+	CostUSD          float64    `json:"costUsd,omitempty"`
 	TrackerPlugin    string     `json:"trackerPlugin"`
 	PRDPath          string     `json:"prdPath"`
 	CurrentIteration int        `json:"currentIteration"`
@@ -58,7 +65,7 @@ type SessionMeta struct {
 	EndedAt          *time.Time `json:"endedAt,omitempty"`
 }
 
-func NewSession(projectDir string, prdPath string, agentName string, maxIter int, p *prd.PRD) *Session {
+func NewSession(projectDir string, prdPath string, agentName string, trackerName string, maxIter int, p *prd.PRD) *Session {
 	now := time.Now().UTC()
 	id := uuid.New().String()
 
@@ -93,7 +100,7 @@ func NewSession(projectDir string, prdPath string, agentName string, maxIter int
 		IsPaused:         false,
 		AgentPlugin:      agentName,
 		TrackerState: &TrackerState{
-			Plugin:     "json",
+			Plugin:     trackerName,
 			PRDPath:    prdPath,
 			TotalTasks: p.TotalCount(),
 			Tasks:      tasks,
@@ -104,6 +111,32 @@ func NewSession(projectDir string, prdPath string, agentName string, maxIter int
 	}
 }
 
+// MarkTaskCompleted records that a task was completed during this session.
+func (s *Session) MarkTaskCompleted(id string) {
+	if s.TrackerState == nil {
+		return
+	}
+	for i := range s.TrackerState.Tasks {
+		if s.TrackerState.Tasks[i].ID == id {
+			s.TrackerState.Tasks[i].Status = "completed"
+			s.TrackerState.Tasks[i].CompletedInSession = true
+		}
+	}
+}
+
+// Load reads the last session saved in projectDir.
+func Load(projectDir string) (*Session, error) {
+	data, err := os.ReadFile(filepath.Join(projectDir, ".ralph-tui", "session.json"))
+	if err != nil {
+		return nil, err
+	}
+	var s Session
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("reading session.json: %w", err)
+	}
+	return &s, nil
+}
+
 func (s *Session) Save(projectDir string) error {
 	dir := filepath.Join(projectDir, ".ralph-tui")
 	if err := os.MkdirAll(dir, 0755); err != nil {
@@ -123,7 +156,10 @@ func (s *Session) SaveMeta(projectDir string) error {
 		StartedAt:        s.StartedAt,
 		UpdatedAt:        s.UpdatedAt,
 		AgentPlugin:      s.AgentPlugin,
-		TrackerPlugin:    "json",
+		Profile:          s.Profile,
+		Server:           s.Server,
+		CostUSD:          s.CostUSD,
+		TrackerPlugin:    s.TrackerState.Plugin,
 		PRDPath:          s.TrackerState.PRDPath,
 		CurrentIteration: s.CurrentIteration,
 		MaxIterations:    s.MaxIterations,
@@ -0,0 +1,383 @@
+package tracker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zhrkvl/ralph-go/internal/prd"
+)
+
+func init() {
+	Register("github", func(opts Options) (Tracker, error) { return newIssues("github", opts) })
+	Register("gitea", func(opts Options) (Tracker, error) { return newIssues("gitea", opts) })
+}
+
+// Issues takes stories from the open issues of a GitHub or Gitea
+// repository. It is the "github" and "gitea" tracker.
+//
+// Issues with the configured label and/or milestone are stories, oldest
+// first. Checkbox items in the issue body are acceptance criteria and the
+// rest of the body is the description. When a story is done ralph comments
+// on the issue with a summary of the iteration and closes it, or adds a
+// label instead. Notes are posted as comments.
+//
+// Settings:
+//
+//	repo = "owner/name"                   # required
+//	baseURL = "https://api.github.com"    # Gitea: https://host/api/v1
+//	label = "ralph"                       # only issues with this label
+//	milestone = "v1.2"                    # only issues in this milestone
+//	token = "..."                         # default: $GITHUB_TOKEN / $GITEA_TOKEN
+//	tokenEnv = "MY_TOKEN"                 # read the token from this variable
+//	onDone = "close"                      # or "label"
+//	doneLabel = "ralph-done"              # label added with onDone = "label"
+type Issues struct {
+	flavor    string // github or gitea
+	baseURL   string
+	repo      string
+	token     string
+	label     string
+	milestone string
+	onDone    string
+	doneLabel string
+	client    *http.Client
+
+	mu     sync.Mutex
+	closed map[int]prd.UserStory // closed by this tracker, still listed as passing
+	notes  map[int][]string      // notes posted by this tracker
+}
+
+func newIssues(flavor string, opts Options) (Tracker, error) {
+	s := opts.Settings
+	t := &Issues{
+		flavor:    flavor,
+		baseURL:   strings.TrimRight(settingString(s, "baseURL"), "/"),
+		repo:      settingString(s, "repo"),
+		token:     settingString(s, "token"),
+		label:     settingString(s, "label"),
+		milestone: settingString(s, "milestone"),
+		onDone:    settingString(s, "onDone"),
+		doneLabel: settingString(s, "doneLabel"),
+		client:    &http.Client{Timeout: 30 * time.Second},
+		closed:    map[int]prd.UserStory{},
+		notes:     map[int][]string{},
+	}
+	if strings.Count(t.repo, "/") != 1 {
+		return nil, fmt.Errorf("%s tracker: set repo = \"owner/name\" under [trackerOptions.%s]", flavor, flavor)
+	}
+	if t.baseURL == "" {
+		if flavor == "gitea" {
+			return nil, fmt.Errorf("gitea tracker: set baseURL (e.g. \"https://gitea.example.com/api/v1\") under [trackerOptions.gitea]")
+		}
+		t.baseURL = "https://api.github.com"
+	}
+	if t.token == "" {
+		env := settingString(s, "tokenEnv")
+		if env == "" {
+			env = strings.ToUpper(flavor) + "_TOKEN"
+		}
+		t.token = os.Getenv(env)
+	}
+	switch t.onDone {
+	case "":
+		t.onDone = "close"
+	case "close", "label":
+	default:
+		return nil, fmt.Errorf("%s tracker: onDone must be \"close\" or \"label\", not %q", flavor, t.onDone)
+	}
+	if t.doneLabel == "" {
+		t.doneLabel = "ralph-done"
+	}
+	return t, nil
+}
+
+func (t *Issues) Name() string { return t.flavor }
+
+func (t *Issues) Path() string { return "" }
+
+type issue struct {
+	Number      int       `json:"number"`
+	Title       string    `json:"title"`
+	Body        string    `json:"body"`
+	State       string    `json:"state"`
+	Labels      []label   `json:"labels"`
+	PullRequest *struct{} `json:"pull_request"`
+}
+
+type label struct {
+	Name string `json:"name"`
+}
+
+func (t *Issues) List() (*prd.PRD, error) {
+	issues, err := t.openIssues()
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	byNumber := make(map[int]prd.UserStory, len(issues)+len(t.closed))
+	for n, s := range t.closed {
+		byNumber[n] = s
+	}
+	for _, is := range issues {
+		s := t.story(is)
+		s.Notes = strings.Join(t.notes[is.Number], "\n")
+		byNumber[is.Number] = s
+	}
+	t.mu.Unlock()
+
+	numbers := make([]int, 0, len(byNumber))
+	for n := range byNumber {
+		numbers = append(numbers, n)
+	}
+	sort.Ints(numbers)
+	p := &prd.PRD{Name: t.repo, UserStories: []prd.UserStory{}}
+	if t.milestone != "" {
+		p.Name += " " + t.milestone
+	}
+	for i, n := range numbers {
+		s := byNumber[n]
+		s.Priority = i + 1
+		p.UserStories = append(p.UserStories, s)
+	}
+	return p, nil
+}
+
+func (t *Issues) Get(id string) (*prd.UserStory, error) {
+	p, err := t.List()
+	if err != nil {
+		return nil, err
+	}
+	return p.Story(id)
+}
+
+func (t *Issues) Next() (*prd.UserStory, error) {
+	p, err := t.List()
+	if err != nil {
+		return nil, err
+	}
+	return p.CurrentStory(), nil
+}
+
+func (t *Issues) MarkDone(id string) error {
+	return t.Complete(id, "")
+}
+
+// Complete comments on the issue and closes or relabels it.
+func (t *Issues) Complete(id, summary string) error {
+	n, err := issueNumber(id)
+	if err != nil {
+		return err
+	}
+	if summary == "" {
+		summary = "Completed by ralph."
+	}
+	if err := t.comment(n, summary); err != nil {
+		return err
+	}
+
+	if t.onDone == "label" {
+		return t.do("POST", t.issuePath(n)+"/labels", map[string]any{"labels": []string{t.doneLabel}}, nil)
+	}
+	var is issue
+	if err := t.do("PATCH", t.issuePath(n), map[string]any{"state": "closed"}, &is); err != nil {
+		return err
+	}
+	s := t.story(is)
+	s.Passes = true
+	t.mu.Lock()
+	s.Notes = strings.Join(t.notes[n], "\n")
+	t.closed[n] = s
+	t.mu.Unlock()
+	return nil
+}
+
+// AddNote posts the note as a comment on the issue.
+func (t *Issues) AddNote(id, note string) error {
+	n, err := issueNumber(id)
+	if err != nil {
+		return err
+	}
+	if err := t.comment(n, note); err != nil {
+		return err
+	}
+	t.mu.Lock()
+	t.notes[n] = append(t.notes[n], note)
+	t.mu.Unlock()
+	return nil
+}
+
+func (t *Issues) comment(n int, body string) error {
+	return t.do("POST", t.issuePath(n)+"/comments", map[string]string{"body": body}, nil)
+}
+
+// openIssues fetches every open issue matching the label and milestone,
+// following pagination.
+func (t *Issues) openIssues() ([]issue, error) {
+	q := url.Values{"state": {"open"}}
+	if t.label != "" {
+		q.Set("labels", t.label)
+	}
+	pageSize := 100
+	if t.flavor == "gitea" {
+		pageSize = 50
+		q.Set("type", "issues")
+		q.Set("limit", strconv.Itoa(pageSize))
+		if t.milestone != "" {
+			q.Set("milestones", t.milestone)
+		}
+	} else {
+		q.Set("per_page", strconv.Itoa(pageSize))
+		if t.milestone != "" {
+			number, err := t.githubMilestone()
+			if err != nil {
+				return nil, err
+			}
+			q.Set("milestone", number)
+		}
+	}
+
+	var all []issue
+	for page := 1; ; page++ {
+		q.Set("page", strconv.Itoa(page))
+		var batch []issue
+		if err := t.do("GET", "/repos/"+t.repo+"/issues?"+q.Encode(), nil, &batch); err != nil {
+			return nil, err
+		}
+		for _, is := range batch {
+			if is.PullRequest == nil {
+				all = append(all, is)
+			}
+		}
+		if len(batch) < pageSize {
+			return all, nil
+		}
+	}
+}
+
+// githubMilestone resolves the milestone setting to the number the GitHub
+// API filters by. Numbers are used as they are.
+func (t *Issues) githubMilestone() (string, error) {
+	if _, err := strconv.Atoi(t.milestone); err == nil {
+		return t.milestone, nil
+	}
+	var milestones []struct {
+		Number int    `json:"number"`
+		Title  string `json:"title"`
+	}
+	if err := t.do("GET", "/repos/"+t.repo+"/milestones?state=all&per_page=100", nil, &milestones); err != nil {
+		return "", err
+	}
+	for _, m := range milestones {
+		if m.Title == t.milestone {
+			return strconv.Itoa(m.Number), nil
+		}
+	}
+	return "", fmt.Errorf("milestone %q not found in %s", t.milestone, t.repo)
+}
+
+func (t *Issues) issuePath(n int) string {
+	return fmt.Sprintf("/repos/%s/issues/%d", t.repo, n)
+}
+
+// do sends a JSON request to the API and decodes the response into out.
+func (t *Issues) do(method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, t.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if t.flavor == "github" {
+		req.Header.Set("Accept", "application/vnd.github+json")
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if t.token != "" {
+		scheme := "Bearer"
+		if t.flavor == "gitea" {
+			scheme = "token"
+		}
+		req.Header.Set("Authorization", scheme+" "+t.token)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %w", t.flavor, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("%s: reading response: %w", t.flavor, err)
+	}
+	if resp.StatusCode/100 != 2 {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		msg := strings.TrimSpace(string(data))
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Message != "" {
+			msg = apiErr.Message
+		}
+		return fmt.Errorf("%s: %s %s: %s: %s", t.flavor, method, strings.SplitN(path, "?", 2)[0], resp.Status, msg)
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("%s: decoding response: %w", t.flavor, err)
+		}
+	}
+	return nil
+}
+
+var issueCheckbox = regexp.MustCompile(`^\s*[-*+]\s+\[([ xX])\]\s+(.*)$`)
+
+// story converts an issue to a story. Checkbox items in the body become
+// acceptance criteria; the rest is the description.
+func (t *Issues) story(is issue) prd.UserStory {
+	s := prd.UserStory{
+		ID:     "#" + strconv.Itoa(is.Number),
+		Title:  is.Title,
+		Passes: is.State == "closed",
+	}
+	for _, l := range is.Labels {
+		if t.onDone == "label" && l.Name == t.doneLabel {
+			s.Passes = true
+		}
+	}
+	var desc []string
+	for _, line := range strings.Split(strings.ReplaceAll(is.Body, "\r\n", "\n"), "\n") {
+		if m := issueCheckbox.FindStringSubmatch(line); m != nil {
+			s.AcceptanceCriteria = append(s.AcceptanceCriteria, strings.TrimSpace(m[2]))
+			continue
+		}
+		desc = append(desc, line)
+	}
+	s.Description = strings.TrimSpace(strings.Join(desc, "\n"))
+	return s
+}
+
+func issueNumber(id string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimPrefix(id, "#"))
+	if err != nil {
+		return 0, fmt.Errorf("%q is not an issue number", id)
+	}
+	return n, nil
+}
@@ -0,0 +1,151 @@
+package tracker
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// fakeIssues is a GitHub issues API holding open issues 1..n, with #2 a
+// pull request. It records the write requests made to it.
+type fakeIssues struct {
+	t     *testing.T
+	mu    sync.Mutex
+	n     int
+	calls []string // "METHOD path body" of every write
+}
+
+func (f *fakeIssues) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+		f.t.Errorf("%s %s: Authorization = %q", r.Method, r.URL.Path, got)
+	}
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/repos/o/r/milestones":
+		fmt.Fprint(w, `[{"number":3,"title":"v1.1"},{"number":4,"title":"v1.2"}]`)
+	case r.Method == "GET" && r.URL.Path == "/repos/o/r/issues":
+		q := r.URL.Query()
+		if q.Get("state") != "open" || q.Get("labels") != "ralph" || q.Get("milestone") != "4" {
+			f.t.Errorf("issues query = %s", r.URL.RawQuery)
+		}
+		page, _ := strconv.Atoi(q.Get("page"))
+		perPage, _ := strconv.Atoi(q.Get("per_page"))
+		f.mu.Lock()
+		n := f.n
+		f.mu.Unlock()
+		batch := []map[string]any{}
+		for i := (page-1)*perPage + 1; i <= min(page*perPage, n); i++ {
+			is := map[string]any{"number": i, "title": fmt.Sprintf("Issue %d", i), "state": "open"}
+			switch i {
+			case 1:
+				is["body"] = "Add the users table.\r\n\r\n- [ ] Migration adds users\r\n- [x] Typecheck passes\r\n"
+			case 2:
+				is["pull_request"] = map[string]any{"url": "x"}
+			}
+			batch = append(batch, is)
+		}
+		json.NewEncoder(w).Encode(batch)
+	default:
+		body, _ := io.ReadAll(r.Body)
+		f.mu.Lock()
+		f.calls = append(f.calls, r.Method+" "+r.URL.Path+" "+string(body))
+		f.mu.Unlock()
+		if r.Method == "PATCH" {
+			fmt.Fprintf(w, `{"number":1,"title":"Issue 1","state":"closed"}`)
+			return
+		}
+		fmt.Fprint(w, `{}`)
+	}
+}
+
+func newFakeIssues(t *testing.T, n int, settings map[string]any) (*Issues, *fakeIssues) {
+	t.Helper()
+	f := &fakeIssues{t: t, n: n}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	s := map[string]any{"repo": "o/r", "baseURL": srv.URL, "token": "secret", "label": "ralph", "milestone": "v1.2"}
+	for k, v := range settings {
+		s[k] = v
+	}
+	tr, err := New("github", Options{Settings: s})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tr.(*Issues), f
+}
+
+func TestIssuesList(t *testing.T) {
+	tr, _ := newFakeIssues(t, 101, nil)
+	p, err := tr.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != "o/r v1.2" {
+		t.Errorf("name = %q", p.Name)
+	}
+	// 101 issues over two pages, less the pull request
+	if len(p.UserStories) != 100 {
+		t.Fatalf("got %d stories, want 100", len(p.UserStories))
+	}
+	s := p.UserStories[0]
+	if s.ID != "#1" || s.Priority != 1 || s.Passes {
+		t.Errorf("first story = %+v", s)
+	}
+	if s.Description != "Add the users table." {
+		t.Errorf("description = %q", s.Description)
+	}
+	if len(s.AcceptanceCriteria) != 2 || s.AcceptanceCriteria[0] != "Migration adds users" || s.AcceptanceCriteria[1] != "Typecheck passes" {
+		t.Errorf("criteria = %q", s.AcceptanceCriteria)
+	}
+	if last := p.UserStories[99]; last.ID != "#101" || last.Priority != 100 {
+		t.Errorf("last story = %s priority %d", last.ID, last.Priority)
+	}
+}
+
+func TestIssuesCompleteClose(t *testing.T) {
+	tr, f := newFakeIssues(t, 1, nil)
+	if err := tr.AddNote("#1", "Needs a migration"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Complete("#1", "Done in abc123"); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`POST /repos/o/r/issues/1/comments {"body":"Needs a migration"}`,
+		`POST /repos/o/r/issues/1/comments {"body":"Done in abc123"}`,
+		`PATCH /repos/o/r/issues/1 {"state":"closed"}`,
+	}
+	if fmt.Sprint(f.calls) != fmt.Sprint(want) {
+		t.Errorf("calls = %q, want %q", f.calls, want)
+	}
+
+	// Closed issues drop out of the API's list but stay as passing stories.
+	f.mu.Lock()
+	f.n = 0
+	f.mu.Unlock()
+	s, err := tr.Get("#1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.Passes || s.Notes != "Needs a migration" {
+		t.Errorf("story after Complete = %+v", s)
+	}
+}
+
+func TestIssuesCompleteLabel(t *testing.T) {
+	tr, f := newFakeIssues(t, 1, map[string]any{"onDone": "label", "doneLabel": "done"})
+	if err := tr.MarkDone("#1"); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`POST /repos/o/r/issues/1/comments {"body":"Completed by ralph."}`,
+		`POST /repos/o/r/issues/1/labels {"labels":["done"]}`,
+	}
+	if fmt.Sprint(f.calls) != fmt.Sprint(want) {
+		t.Errorf("calls = %q, want %q", f.calls, want)
+	}
+}
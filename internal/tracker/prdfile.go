@@ -0,0 +1,89 @@
+package tracker
+
+import (
+	"path/filepath"
+
+	"github.com/zhrkvl/ralph-go/internal/prd"
+)
+
+func init() {
+	Register("json", newPRDFile)
+}
+
+// PRDFile keeps stories in a PRD file (prd.json, prd.yaml or prd.md) that
+// the agent reads and updates itself. It is the "json" tracker.
+//
+// Settings:
+//
+//	path = "docs/prd.yaml"  # PRD file, relative to the project dir
+//	                        # (default: the PRD in the ralph dir)
+type PRDFile struct {
+	path string
+}
+
+func newPRDFile(opts Options) (Tracker, error) {
+	path := settingString(opts.Settings, "path")
+	switch {
+	case path == "":
+		if path = prd.Find(opts.RalphDir); path == "" {
+			path = filepath.Join(opts.RalphDir, "prd.json")
+		}
+	case !filepath.IsAbs(path):
+		path = filepath.Join(opts.ProjectDir, path)
+	}
+	return &PRDFile{path: path}, nil
+}
+
+func (t *PRDFile) Name() string { return "json" }
+
+func (t *PRDFile) Path() string { return t.path }
+
+func (t *PRDFile) List() (*prd.PRD, error) { return prd.Load(t.path) }
+
+func (t *PRDFile) Get(id string) (*prd.UserStory, error) {
+	p, err := prd.Load(t.path)
+	if err != nil {
+		return nil, err
+	}
+	return p.Story(id)
+}
+
+func (t *PRDFile) Next() (*prd.UserStory, error) {
+	p, err := prd.Load(t.path)
+	if err != nil {
+		return nil, err
+	}
+	return p.CurrentStory(), nil
+}
+
+func (t *PRDFile) MarkDone(id string) error {
+	_, err := prd.Update(t.path, func(p *prd.PRD) error {
+		s, err := p.Story(id)
+		if err != nil {
+			return err
+		}
+		s.Passes = true
+		return nil
+	})
+	return err
+}
+
+func (t *PRDFile) AddNote(id, note string) error {
+	_, err := prd.Update(t.path, func(p *prd.PRD) error {
+		s, err := p.Story(id)
+		if err != nil {
+			return err
+		}
+		s.AddNote(note)
+		return nil
+	})
+	return err
+}
+
+func (t *PRDFile) Update(fn func(*prd.PRD) error) (*prd.PRD, error) {
+	return prd.Update(t.path, fn)
+}
+
+func (t *PRDFile) Validate() ([]prd.Issue, error) {
+	return prd.ValidateFile(t.path)
+}
@@ -0,0 +1,337 @@
+package tracker
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/zhrkvl/ralph-go/internal/prd"
+)
+
+// SyncState records what the stories looked like after the last sync. It
+// is kept in .ralph-tui/sync.json; a side changed a field if it differs
+// from the snapshot here.
+type SyncState struct {
+	Tracker  string                   `json:"tracker"`
+	SyncedAt time.Time                `json:"syncedAt"`
+	Stories  map[string]StorySnapshot `json:"stories"`
+}
+
+// StorySnapshot holds the synced fields of a story.
+type StorySnapshot struct {
+	Title              string   `json:"title"`
+	Description        string   `json:"description,omitempty"`
+	AcceptanceCriteria []string `json:"acceptanceCriteria,omitempty"`
+	Passes             bool     `json:"passes"`
+	Notes              string   `json:"notes,omitempty"` // local notes, pushed as of the last sync
+}
+
+func syncStatePath(projectDir string) string {
+	return filepath.Join(projectDir, ".ralph-tui", "sync.json")
+}
+
+// LoadSyncState reads .ralph-tui/sync.json. A missing file is an empty
+// state: the first sync has nothing to compare against.
+func LoadSyncState(projectDir string) (*SyncState, error) {
+	st := &SyncState{Stories: map[string]StorySnapshot{}}
+	data, err := os.ReadFile(syncStatePath(projectDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return st, nil
+		}
+		return nil, fmt.Errorf("reading sync state: %w", err)
+	}
+	if err := json.Unmarshal(data, st); err != nil {
+		return nil, fmt.Errorf("parsing sync.json: %w", err)
+	}
+	if st.Stories == nil {
+		st.Stories = map[string]StorySnapshot{}
+	}
+	return st, nil
+}
+
+// Save writes the state to .ralph-tui/sync.json.
+func (st *SyncState) Save(projectDir string) error {
+	if err := os.MkdirAll(filepath.Dir(syncStatePath(projectDir)), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	return prd.WriteFileAtomic(syncStatePath(projectDir), append(data, '\n'))
+}
+
+// SyncAction is one thing a sync did, or would do.
+type SyncAction struct {
+	Kind    string // pulled, pushed, updated, conflict, skipped
+	ID      string
+	Message string
+}
+
+func (a SyncAction) String() string {
+	return fmt.Sprintf("%-8s %-8s %s", a.Kind, a.ID, a.Message)
+}
+
+// SyncReport lists what a sync did.
+type SyncReport struct {
+	Actions []SyncAction
+}
+
+// Conflicts returns the number of fields changed on both sides.
+func (r *SyncReport) Conflicts() int {
+	n := 0
+	for _, a := range r.Actions {
+		if a.Kind == "conflict" {
+			n++
+		}
+	}
+	return n
+}
+
+func (r *SyncReport) add(kind, id, format string, args ...any) {
+	r.Actions = append(r.Actions, SyncAction{Kind: kind, ID: id, Message: fmt.Sprintf(format, args...)})
+}
+
+// Sync reconciles the PRD the agent edits (local) with another tracker
+// (remote) using the state from the last sync:
+//
+//   - stories new in remote are added to local;
+//   - title, description and criteria follow remote unless only local
+//     changed them;
+//   - passes flips are pushed with MarkDone or pulled, whichever side
+//     changed; a story that left remote (e.g. a closed issue) passes;
+//   - notes added locally are pushed with AddNote;
+//   - a field changed differently on both sides is reported as a
+//     conflict and left alone on both sides.
+//
+// The PRD is written before anything is pushed, so a PRD that cannot be
+// written leaves remote untouched and the next sync pushes the same
+// changes once. With dryRun nothing is written. The returned state is the
+// one to save.
+func Sync(local, remote Tracker, state *SyncState, dryRun bool) (*SyncReport, *SyncState, error) {
+	editor, ok := local.(Editor)
+	if !ok {
+		return nil, nil, fmt.Errorf("cannot sync into the %s tracker: its stories are read-only", local.Name())
+	}
+	lp, err := local.List()
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading local stories: %w", err)
+	}
+	rp, err := remote.List()
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading %s stories: %w", remote.Name(), err)
+	}
+	base := state.Stories
+	if state.Tracker != remote.Name() {
+		base = map[string]StorySnapshot{}
+	}
+
+	report := &SyncReport{}
+	next := &SyncState{Tracker: remote.Name(), SyncedAt: time.Now().UTC(), Stories: map[string]StorySnapshot{}}
+	var pulled []prd.UserStory
+	edits := map[string]func(*prd.UserStory){}
+	var pushes []pendingPush
+	inRemote := map[string]bool{}
+
+	for _, r := range rp.UserStories {
+		inRemote[r.ID] = true
+		b, synced := base[r.ID]
+		ls, err := lp.Story(r.ID)
+		if err != nil {
+			if synced {
+				report.add("skipped", r.ID, "deleted locally, not pulled again")
+				next.Stories[r.ID] = b
+				continue
+			}
+			report.add("pulled", r.ID, "%s", r.Title)
+			s := r
+			s.Notes = ""
+			pulled = append(pulled, s)
+			next.Stories[r.ID] = snapshot(r, "")
+			continue
+		}
+		l := *ls
+		if !synced {
+			// First sync of this story: the tracker's content wins, a
+			// story passes if either side says so and existing notes
+			// are not pushed.
+			b = snapshot(l, l.Notes)
+			b.Passes = l.Passes && r.Passes
+		}
+		snap, edit, push := mergeStory(report, remote, l, r, b)
+		next.Stories[r.ID] = snap
+		if edit != nil {
+			edits[r.ID] = edit
+		}
+		pushes = append(pushes, push...)
+	}
+
+	for _, l := range lp.UserStories {
+		if inRemote[l.ID] {
+			continue
+		}
+		b, synced := base[l.ID]
+		switch {
+		case !synced:
+			// Only in the PRD: it stays there.
+		case !l.Passes && l.Passes == b.Passes:
+			report.add("updated", l.ID, "passes (no longer open in %s)", remote.Name())
+			edits[l.ID] = func(s *prd.UserStory) { s.Passes = true }
+			b.Passes = true
+			next.Stories[l.ID] = b
+		default:
+			next.Stories[l.ID] = b
+		}
+	}
+
+	if !dryRun && (len(pulled) > 0 || len(edits) > 0) {
+		if err := updateLocal(editor, edits, pulled); err != nil {
+			return report, nil, fmt.Errorf("updating the PRD: %w", err)
+		}
+	}
+	for _, p := range pushes {
+		s := next.Stories[p.id]
+		p.run(&s, dryRun)
+		next.Stories[p.id] = s
+	}
+	return report, next, nil
+}
+
+// updateLocal applies the edits and adds the pulled stories to the PRD.
+func updateLocal(editor Editor, edits map[string]func(*prd.UserStory), pulled []prd.UserStory) error {
+	_, err := editor.Update(func(p *prd.PRD) error {
+		for i := range p.UserStories {
+			if edit, ok := edits[p.UserStories[i].ID]; ok {
+				edit(&p.UserStories[i])
+			}
+		}
+		for _, s := range pulled {
+			if p.Index(s.ID) >= 0 {
+				continue
+			}
+			s.Priority = p.MaxPriority() + 1
+			p.UserStories = append(p.UserStories, s)
+		}
+		return nil
+	})
+	return err
+}
+
+// pendingPush is a change to push to remote once the PRD is written. run
+// reports it and, if it went out, records it in the story's snapshot.
+type pendingPush struct {
+	id  string
+	run func(next *StorySnapshot, dryRun bool)
+}
+
+// mergeStory reconciles one story present on both sides against its base.
+// It returns the snapshot to keep, the local edit to apply and the changes
+// to push to remote after that.
+func mergeStory(report *SyncReport, remote Tracker, l, r prd.UserStory, b StorySnapshot) (StorySnapshot, func(*prd.UserStory), []pendingPush) {
+	next := b
+	var pull []string
+	var apply []func(*prd.UserStory)
+	var pushes []pendingPush
+
+	// Content: remote is the source, local edits are kept but not pushed.
+	field := func(name string, lv, rv, bv any, set func(*prd.UserStory), record func()) {
+		lc, rc := !equalValue(lv, bv), !equalValue(rv, bv)
+		switch {
+		case rc && lc && !equalValue(lv, rv):
+			report.add("conflict", l.ID, "%s changed in the PRD and in %s", name, remote.Name())
+		case rc:
+			record()
+			if !equalValue(lv, rv) {
+				pull = append(pull, name)
+				apply = append(apply, set)
+			}
+		}
+	}
+	field("title", l.Title, r.Title, b.Title,
+		func(s *prd.UserStory) { s.Title = r.Title }, func() { next.Title = r.Title })
+	field("description", l.Description, r.Description, b.Description,
+		func(s *prd.UserStory) { s.Description = r.Description }, func() { next.Description = r.Description })
+	field("acceptanceCriteria", l.AcceptanceCriteria, r.AcceptanceCriteria, b.AcceptanceCriteria,
+		func(s *prd.UserStory) { s.AcceptanceCriteria = r.AcceptanceCriteria }, func() { next.AcceptanceCriteria = r.AcceptanceCriteria })
+
+	// Passes goes both ways.
+	lc, rc := l.Passes != b.Passes, r.Passes != b.Passes
+	switch {
+	case lc && rc && l.Passes != r.Passes:
+		report.add("conflict", l.ID, "passes is %v in the PRD and %v in %s", l.Passes, r.Passes, remote.Name())
+	case rc:
+		next.Passes = r.Passes
+		if l.Passes != r.Passes {
+			pull = append(pull, "passes")
+			apply = append(apply, func(s *prd.UserStory) { s.Passes = r.Passes })
+		}
+	case lc && l.Passes:
+		pushes = append(pushes, pendingPush{l.ID, func(next *StorySnapshot, dryRun bool) {
+			if err := push(dryRun, func() error { return remote.MarkDone(l.ID) }); err != nil {
+				report.add("skipped", l.ID, "marking as done in %s: %v", remote.Name(), err)
+			} else {
+				report.add("pushed", l.ID, "passes")
+				next.Passes = true
+			}
+		}})
+	case lc:
+		report.add("skipped", l.ID, "reopened in the PRD; reopen it in %s by hand", remote.Name())
+	}
+
+	// Notes only go out: push what was added since the last sync.
+	if l.Notes != b.Notes {
+		note := strings.TrimSpace(strings.TrimPrefix(l.Notes, b.Notes))
+		if note == "" {
+			next.Notes = l.Notes
+		} else {
+			pushes = append(pushes, pendingPush{l.ID, func(next *StorySnapshot, dryRun bool) {
+				if err := push(dryRun, func() error { return remote.AddNote(l.ID, note) }); err != nil {
+					report.add("skipped", l.ID, "adding note in %s: %v", remote.Name(), err)
+				} else {
+					report.add("pushed", l.ID, "notes")
+					next.Notes = l.Notes
+				}
+			}})
+		}
+	}
+
+	if len(pull) == 0 {
+		return next, nil, pushes
+	}
+	report.add("updated", l.ID, "%s", strings.Join(pull, ", "))
+	return next, func(s *prd.UserStory) {
+		for _, f := range apply {
+			f(s)
+		}
+	}, pushes
+}
+
+func push(dryRun bool, fn func() error) error {
+	if dryRun {
+		return nil
+	}
+	return fn()
+}
+
+func snapshot(s prd.UserStory, notes string) StorySnapshot {
+	return StorySnapshot{
+		Title:              s.Title,
+		Description:        s.Description,
+		AcceptanceCriteria: s.AcceptanceCriteria,
+		Passes:             s.Passes,
+		Notes:              notes,
+	}
+}
+
+func equalValue(a, b any) bool {
+	if as, ok := a.([]string); ok {
+		bs, _ := b.([]string)
+		return slices.Equal(as, bs)
+	}
+	return a == b
+}
@@ -0,0 +1,92 @@
+package tracker
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zhrkvl/ralph-go/internal/prd"
+)
+
+// memRemote is a remote tracker holding its stories in memory and
+// recording what is pushed to it.
+type memRemote struct {
+	p      *prd.PRD
+	pushed []string
+}
+
+func (m *memRemote) Name() string                          { return "mem" }
+func (m *memRemote) List() (*prd.PRD, error)               { return m.p, nil }
+func (m *memRemote) Get(id string) (*prd.UserStory, error) { return m.p.Story(id) }
+func (m *memRemote) Next() (*prd.UserStory, error)         { return m.p.CurrentStory(), nil }
+func (m *memRemote) Path() string                          { return "" }
+
+func (m *memRemote) MarkDone(id string) error {
+	m.pushed = append(m.pushed, "done "+id)
+	return nil
+}
+
+func (m *memRemote) AddNote(id, note string) error {
+	m.pushed = append(m.pushed, "note "+id+" "+note)
+	return nil
+}
+
+// brokenEditor fails its updates while broken is set.
+type brokenEditor struct {
+	*TaskList
+	broken bool
+}
+
+func (e *brokenEditor) Update(fn func(*prd.PRD) error) (*prd.PRD, error) {
+	if e.broken {
+		return nil, errors.New("disk full")
+	}
+	return e.TaskList.Update(fn)
+}
+
+func TestSyncPushesOnceWhenLocalWriteFails(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "TODO.md")
+	if err := os.WriteFile(path, []byte("- [x] US-001: First\n  > Used a migration\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tl, err := New("markdown", Options{ProjectDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	local := &brokenEditor{TaskList: tl.(*TaskList), broken: true}
+	// Since the last sync the story passed and got a note locally, and
+	// was renamed remotely.
+	remote := &memRemote{p: &prd.PRD{UserStories: []prd.UserStory{{ID: "US-001", Title: "First story", Priority: 1}}}}
+	state := &SyncState{Tracker: "mem", Stories: map[string]StorySnapshot{"US-001": {Title: "First"}}}
+
+	if _, _, err := Sync(local, remote, state, false); err == nil {
+		t.Fatal("sync with a failing PRD write succeeded")
+	}
+	if len(remote.pushed) != 0 {
+		t.Fatalf("pushed %q although the PRD was not written", remote.pushed)
+	}
+
+	local.broken = false
+	_, next, err := Sync(local, remote, state, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"done US-001", "note US-001 Used a migration"}
+	if fmt.Sprint(remote.pushed) != fmt.Sprint(want) {
+		t.Errorf("pushed %q, want %q", remote.pushed, want)
+	}
+	if s, _ := tl.Get("US-001"); s.Title != "First story" {
+		t.Errorf("title not pulled: %q", s.Title)
+	}
+
+	remote.pushed = nil
+	if _, _, err := Sync(local, remote, next, false); err != nil {
+		t.Fatal(err)
+	}
+	if len(remote.pushed) != 0 {
+		t.Errorf("pushed again: %q", remote.pushed)
+	}
+}
@@ -0,0 +1,641 @@
+package tracker
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/zhrkvl/ralph-go/internal/prd"
+)
+
+func init() {
+	Register("markdown", newTaskList)
+}
+
+// TaskList keeps stories as a GitHub-style task list in a Markdown file
+// such as TODO.md. It is the "markdown" tracker.
+//
+//	# Project name
+//
+//	- [ ] US-001: Add the users table
+//	  - Migration adds users(id, email)
+//	  - Typecheck passes
+//	- [x] Set up CI
+//
+//	## [ ] Login page
+//
+//	Optional description.
+//
+//	- [ ] Form validates the email
+//	> Notes are quoted lines.
+//
+// Every checkbox list item that is not nested in another story, and every
+// heading that starts with a checkbox, is a story; a ticked box means it
+// passes. Nested list items are acceptance criteria, quoted lines are
+// notes and other text is the description. Stories are worked on in file
+// order. An "ID: " prefix on the title gives a story its ID; stories
+// without one are numbered T-1, T-2, ... in file order until the first
+// save, which writes those IDs into the file so that notes, markers and
+// sync state keep pointing at the same story when others are added,
+// deleted or moved.
+//
+// Saving rewrites only the lines of stories that changed, and the first
+// line of stories that had no ID.
+//
+// Settings:
+//
+//	path = "TODO.md"              # relative to the project dir (default: the
+//	                              # first of TODO.md, tasks.md, TASKS.md,
+//	                              # todo.md that exists)
+//	branchName = "ralph/todo"     # branch for archiving, as in prd.json
+type TaskList struct {
+	path   string
+	branch string
+}
+
+// taskListFiles are the files the markdown tracker looks for in the
+// project dir when no path is configured.
+var taskListFiles = []string{"TODO.md", "tasks.md", "TASKS.md", "todo.md"}
+
+func newTaskList(opts Options) (Tracker, error) {
+	path := settingString(opts.Settings, "path")
+	switch {
+	case path == "":
+		for _, name := range taskListFiles {
+			if _, err := os.Stat(filepath.Join(opts.ProjectDir, name)); err == nil {
+				path = filepath.Join(opts.ProjectDir, name)
+				break
+			}
+		}
+		if path == "" {
+			return nil, fmt.Errorf("no task list found in %s (looked for %s); set path under [trackerOptions.markdown]",
+				opts.ProjectDir, strings.Join(taskListFiles, ", "))
+		}
+	case !filepath.IsAbs(path):
+		path = filepath.Join(opts.ProjectDir, path)
+	}
+	return &TaskList{path: path, branch: settingString(opts.Settings, "branchName")}, nil
+}
+
+func (t *TaskList) Name() string { return "markdown" }
+
+func (t *TaskList) Path() string { return t.path }
+
+func (t *TaskList) List() (*prd.PRD, error) {
+	doc, _, err := t.load()
+	if err != nil {
+		return nil, err
+	}
+	return doc.prd(t.branch), nil
+}
+
+func (t *TaskList) Get(id string) (*prd.UserStory, error) {
+	p, err := t.List()
+	if err != nil {
+		return nil, err
+	}
+	return p.Story(id)
+}
+
+func (t *TaskList) Next() (*prd.UserStory, error) {
+	p, err := t.List()
+	if err != nil {
+		return nil, err
+	}
+	return p.CurrentStory(), nil
+}
+
+func (t *TaskList) MarkDone(id string) error {
+	_, err := t.Update(func(p *prd.PRD) error {
+		s, err := p.Story(id)
+		if err != nil {
+			return err
+		}
+		s.Passes = true
+		return nil
+	})
+	return err
+}
+
+func (t *TaskList) AddNote(id, note string) error {
+	_, err := t.Update(func(p *prd.PRD) error {
+		s, err := p.Story(id)
+		if err != nil {
+			return err
+		}
+		s.AddNote(note)
+		return nil
+	})
+	return err
+}
+
+// Update applies fn to the stories and writes the changes back into the
+// file, leaving everything else as it was. Priorities map to file order,
+// so changing one moves the story. If the file changes while the update
+// is being made it is retried against the new content; if it keeps
+// changing, the update is given up with a *prd.ConflictError.
+func (t *TaskList) Update(fn func(*prd.PRD) error) (*prd.PRD, error) {
+	var changed []string
+	for range taskListRetries {
+		doc, data, err := t.load()
+		if err != nil {
+			return nil, err
+		}
+		p := doc.prd(t.branch)
+		if err := fn(p); err != nil {
+			return nil, err
+		}
+		out, err := doc.patch(p)
+		if err != nil {
+			return nil, err
+		}
+		if bytes.Equal(out, data) {
+			return p, nil
+		}
+		err = prd.ReplaceFile(t.path, data, out)
+		if errors.Is(err, prd.ErrChanged) {
+			changed = changedStories(doc.prd(t.branch), p)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return parseTaskList(out).prd(t.branch), nil
+	}
+	return nil, &prd.ConflictError{Path: t.path, Conflicts: changed}
+}
+
+// taskListRetries is how many times Update tries to get its change in
+// while the file is being changed by someone else.
+const taskListRetries = 3
+
+// changedStories returns the IDs of the stories that differ between
+// before and after, for reporting an update that did not go in.
+func changedStories(before, after *prd.PRD) []string {
+	var ids []string
+	for _, s := range after.UserStories {
+		if old, err := before.Story(s.ID); err != nil || !reflect.DeepEqual(*old, s) {
+			ids = append(ids, s.ID)
+		}
+	}
+	return ids
+}
+
+func (t *TaskList) Validate() ([]prd.Issue, error) {
+	doc, _, err := t.load()
+	if err != nil {
+		return nil, err
+	}
+	var issues []prd.Issue
+	if len(doc.tasks) == 0 {
+		issues = append(issues, prd.Issue{Severity: prd.SeverityWarning, Message: "no tasks found; stories are checkbox items such as \"- [ ] Title\""})
+	}
+	seen := map[string]int{}
+	for i, task := range doc.tasks {
+		path := fmt.Sprintf("/userStories/%d", i)
+		if task.title == "" {
+			issues = append(issues, prd.Issue{Severity: prd.SeverityError, Line: task.start + 1, Column: 1, Path: path + "/title", Message: "story has an empty title"})
+		}
+		if first, dup := seen[task.id]; dup {
+			issues = append(issues, prd.Issue{Severity: prd.SeverityError, Line: task.start + 1, Column: 1, Path: path + "/id",
+				Message: fmt.Sprintf("duplicate story id %q (first used on line %d)", task.id, first)})
+			continue
+		}
+		seen[task.id] = task.start + 1
+	}
+	return issues, nil
+}
+
+func (t *TaskList) load() (*taskDoc, []byte, error) {
+	data, err := os.ReadFile(t.path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading %s: %w", filepath.Base(t.path), err)
+	}
+	return parseTaskList(data), data, nil
+}
+
+var (
+	taskItem    = regexp.MustCompile(`^(\s*)([-*+]|\d+[.)])(\s+)\[([ xX])\]\s+(.*)$`)
+	taskHeading = regexp.MustCompile(`^(#{1,6}\s+)\[([ xX])\]\s+(.*?)\s*#*\s*$`)
+	anyHeading  = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	listItem    = regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)])\s+(?:\[([ xX])\]\s+)?(.*)$`)
+	explicitID  = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9]*-\d+):\s+(.*)$`)
+)
+
+// taskDoc is a parsed task list with the line ranges of its stories.
+type taskDoc struct {
+	lines []string
+	name  string
+	tasks []task
+}
+
+type task struct {
+	start, end int // line range, without trailing blank lines
+	heading    bool
+	prefix     string // first line up to the checkbox: "  - " or "## "
+	bodyIndent string
+	itemIndent int // indentation width of a list story
+
+	checked    bool
+	id         string
+	explicitID bool
+	title      string
+
+	description     []string
+	criteria        []string
+	checkedCriteria map[string]bool
+	boxedCriteria   bool // criteria are written as checkboxes
+	notes           []string
+	noteLines       []int // file line numbers of the notes
+}
+
+func parseTaskList(data []byte) *taskDoc {
+	doc := &taskDoc{lines: prd.SplitLines(data)}
+	var cur *task
+	inFence := false
+
+	finish := func(n int) {
+		end := n
+		for end > cur.start+1 && strings.TrimSpace(doc.lines[end-1]) == "" {
+			end--
+		}
+		cur.end = end
+		doc.tasks = append(doc.tasks, *cur)
+		cur = nil
+	}
+
+	for n, line := range doc.lines {
+		fence := prd.IsFence(line)
+		blank := strings.TrimSpace(line) == ""
+		if !inFence {
+			if cur != nil && !cur.heading && !blank && indentWidth(line) <= cur.itemIndent {
+				finish(n)
+			}
+			if !fence {
+				if m := anyHeading.FindStringSubmatch(line); m != nil {
+					if cur != nil {
+						finish(n)
+					}
+					if hm := taskHeading.FindStringSubmatch(line); hm != nil {
+						cur = &task{start: n, heading: true, prefix: hm[1], checked: hm[2] != " "}
+						cur.setLabel(hm[3])
+					} else if m[1] == "#" && doc.name == "" {
+						doc.name = m[2]
+					}
+					continue
+				}
+				if m := taskItem.FindStringSubmatch(line); m != nil && cur == nil {
+					cur = &task{
+						start:      n,
+						prefix:     m[1] + m[2] + m[3],
+						bodyIndent: m[1] + strings.Repeat(" ", len(m[2])+len(m[3])),
+						itemIndent: indentWidth(line),
+						checked:    m[4] != " ",
+					}
+					cur.setLabel(m[5])
+					continue
+				}
+			}
+		}
+		if fence {
+			inFence = !inFence
+		}
+		if cur != nil {
+			cur.addLine(n, line, inFence || fence)
+		}
+	}
+	if cur != nil {
+		finish(len(doc.lines))
+	}
+	doc.numberTasks()
+	return doc
+}
+
+// numberTasks gives the stories without an ID their position as T-n, or
+// the next free number after it when a story already has that ID written
+// down.
+func (d *taskDoc) numberTasks() {
+	used := map[string]bool{}
+	for _, t := range d.tasks {
+		if t.explicitID {
+			used[t.id] = true
+		}
+	}
+	for i := range d.tasks {
+		t := &d.tasks[i]
+		if t.explicitID {
+			continue
+		}
+		n := i + 1
+		for used["T-"+strconv.Itoa(n)] {
+			n++
+		}
+		t.id = "T-" + strconv.Itoa(n)
+		used[t.id] = true
+	}
+}
+
+func (t *task) setLabel(label string) {
+	t.title = strings.TrimSpace(label)
+	if m := explicitID.FindStringSubmatch(t.title); m != nil {
+		t.id, t.title, t.explicitID = m[1], strings.TrimSpace(m[2]), true
+	}
+}
+
+// addLine sorts a line of the story's body into description, acceptance
+// criteria or notes.
+func (t *task) addLine(n int, line string, code bool) {
+	text := strings.TrimSpace(line)
+	switch {
+	case code:
+		t.description = append(t.description, strings.TrimPrefix(line, t.bodyIndent))
+	case text == "":
+		t.description = append(t.description, "")
+	case strings.HasPrefix(text, ">"):
+		t.notes = append(t.notes, strings.TrimSpace(strings.TrimPrefix(text, ">")))
+		t.noteLines = append(t.noteLines, n)
+	default:
+		if m := listItem.FindStringSubmatch(line); m != nil {
+			criterion := strings.TrimSpace(m[2])
+			t.criteria = append(t.criteria, criterion)
+			if m[1] != "" {
+				t.boxedCriteria = true
+				if t.checkedCriteria == nil {
+					t.checkedCriteria = map[string]bool{}
+				}
+				t.checkedCriteria[criterion] = m[1] != " "
+			}
+			return
+		}
+		t.description = append(t.description, text)
+	}
+}
+
+func (t *task) story(position int) prd.UserStory {
+	return prd.UserStory{
+		ID:                 t.id,
+		Title:              t.title,
+		Description:        strings.TrimSpace(strings.Join(t.description, "\n")),
+		AcceptanceCriteria: t.criteria,
+		Priority:           position,
+		Passes:             t.checked,
+		Notes:              strings.Join(t.notes, "\n"),
+	}
+}
+
+func (d *taskDoc) prd(branch string) *prd.PRD {
+	p := &prd.PRD{Name: d.name, BranchName: branch, UserStories: []prd.UserStory{}}
+	for i := range d.tasks {
+		p.UserStories = append(p.UserStories, d.tasks[i].story(i+1))
+	}
+	return p
+}
+
+// patch renders p into the document's lines. Stories keep their place in
+// the file unless their order changed; new stories are added after the
+// last one.
+func (d *taskDoc) patch(p *prd.PRD) ([]byte, error) {
+	old := d.prd("")
+	byID := make(map[string]int, len(d.tasks))
+	for i, s := range old.UserStories {
+		byID[s.ID] = i
+	}
+	seen := map[string]bool{}
+	for _, s := range p.UserStories {
+		if seen[s.ID] {
+			return nil, fmt.Errorf("story %s already exists", s.ID)
+		}
+		seen[s.ID] = true
+	}
+
+	order := storyOrder(old, p)
+
+	// Stories that are still there fill the slots of the stories that
+	// are still there, in their new order; deleted stories leave with
+	// their slot and new stories are added after the last story.
+	var blocks, added [][]string
+	for _, s := range order {
+		if i, ok := byID[s.ID]; ok {
+			blocks = append(blocks, d.tasks[i].patch(d.lines, old.UserStories[i], s))
+		}
+	}
+	style := d.newTask()
+	for _, s := range order {
+		if _, ok := byID[s.ID]; !ok {
+			added = append(added, style.render(s))
+		}
+	}
+
+	var out []string
+	prev, k := 0, 0
+	for _, t := range d.tasks {
+		out = append(out, d.lines[prev:t.start]...)
+		prev = t.end
+		if seen[t.id] {
+			b := blocks[k]
+			k++
+			// A heading moved below a list item needs a blank line.
+			if len(out) > 0 && strings.TrimSpace(out[len(out)-1]) != "" && strings.HasPrefix(b[0], "#") {
+				out = append(out, "")
+			}
+			out = append(out, b...)
+			continue
+		}
+		// Deleted: also drop the blank line that separated it.
+		if prev < len(d.lines) && strings.TrimSpace(d.lines[prev]) == "" && (len(out) == 0 || strings.TrimSpace(out[len(out)-1]) == "") {
+			prev++
+		}
+	}
+	insertAt := len(out)
+	if len(d.tasks) == 0 {
+		insertAt = len(d.lines)
+	}
+	out = append(out, d.lines[prev:]...)
+
+	// Headings are separated by a blank line, list items are not.
+	var lines []string
+	for i, b := range added {
+		if style.heading || (i == 0 && k == 0) {
+			if i > 0 || (insertAt > 0 && strings.TrimSpace(out[insertAt-1]) != "") {
+				lines = append(lines, "")
+			}
+		}
+		lines = append(lines, b...)
+	}
+	out = slices.Insert(out, insertAt, lines...)
+	for len(out) > 0 && strings.TrimSpace(out[len(out)-1]) == "" {
+		out = out[:len(out)-1]
+	}
+	return prd.JoinLines(out), nil
+}
+
+// storyOrder returns the stories of p in file order. The list order of p
+// wins unless a priority changed; then stories are sorted by priority and
+// a moved story goes in front of (raised) or behind (lowered) the stories
+// it now shares a priority with.
+func storyOrder(old, p *prd.PRD) []prd.UserStory {
+	order := append([]prd.UserStory(nil), p.UserStories...)
+	before := map[string]int{}
+	for _, s := range old.UserStories {
+		before[s.ID] = s.Priority
+	}
+	changed := false
+	for _, s := range order {
+		if prio, ok := before[s.ID]; ok && prio != s.Priority {
+			changed = true
+		}
+	}
+	if !changed {
+		return order
+	}
+	bias := func(s prd.UserStory) int {
+		prio, ok := before[s.ID]
+		switch {
+		case !ok || prio == s.Priority:
+			return 0
+		case s.Priority < prio:
+			return -1
+		}
+		return 1
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		if order[i].Priority != order[j].Priority {
+			return order[i].Priority < order[j].Priority
+		}
+		return bias(order[i]) < bias(order[j])
+	})
+	return order
+}
+
+// newTask returns an empty story shaped like the last one in the file, so
+// added stories match the list or heading style already in use.
+func (d *taskDoc) newTask() task {
+	if len(d.tasks) == 0 {
+		return task{prefix: "- ", bodyIndent: "  "}
+	}
+	last := d.tasks[len(d.tasks)-1]
+	return task{heading: last.heading, prefix: last.prefix, bodyIndent: last.bodyIndent, boxedCriteria: last.boxedCriteria}
+}
+
+// patch returns the lines of an existing story updated from old to cur.
+// A story numbered by its position gets that number written down.
+func (t *task) patch(lines []string, old, cur prd.UserStory) []string {
+	block := append([]string(nil), lines[t.start:t.end]...)
+	if cur.Description != old.Description || !slices.Equal(cur.AcceptanceCriteria, old.AcceptanceCriteria) {
+		return t.render(cur)
+	}
+	if !t.explicitID || cur.ID != old.ID || cur.Title != old.Title || cur.Passes != old.Passes {
+		block[0] = t.firstLine(cur)
+	}
+	if cur.Notes != old.Notes {
+		at := len(block)
+		if len(t.noteLines) > 0 {
+			at = t.noteLines[0] - t.start
+		}
+		var kept []string
+		for i, l := range block {
+			if i == at {
+				kept = append(kept, t.noteBlock(cur.Notes)...)
+			}
+			if !slices.Contains(t.noteLines, t.start+i) {
+				kept = append(kept, l)
+			}
+		}
+		if at == len(block) {
+			if t.heading && strings.TrimSpace(block[len(block)-1]) != "" && cur.Notes != "" {
+				kept = append(kept, "")
+			}
+			kept = append(kept, t.noteBlock(cur.Notes)...)
+		}
+		for len(kept) > 1 && strings.TrimSpace(kept[len(kept)-1]) == "" {
+			kept = kept[:len(kept)-1]
+		}
+		block = kept
+	}
+	return block
+}
+
+func (t *task) firstLine(s prd.UserStory) string {
+	box := "[ ]"
+	if s.Passes {
+		box = "[x]"
+	}
+	return t.prefix + box + " " + s.ID + ": " + s.Title
+}
+
+func (t *task) noteBlock(notes string) []string {
+	if notes == "" {
+		return nil
+	}
+	var out []string
+	for _, l := range strings.Split(notes, "\n") {
+		out = append(out, t.bodyIndent+"> "+l)
+	}
+	return out
+}
+
+// render writes a whole story. Criteria keep their ticks when their text
+// did not change.
+func (t *task) render(s prd.UserStory) []string {
+	out := []string{t.firstLine(s)}
+	var groups [][]string
+	if s.Description != "" {
+		var desc []string
+		for _, l := range strings.Split(s.Description, "\n") {
+			if l == "" {
+				desc = append(desc, "")
+			} else {
+				desc = append(desc, t.bodyIndent+l)
+			}
+		}
+		groups = append(groups, desc)
+	}
+	if len(s.AcceptanceCriteria) > 0 {
+		var crit []string
+		for _, c := range s.AcceptanceCriteria {
+			box := ""
+			if t.boxedCriteria {
+				box = "[ ] "
+				if t.checkedCriteria[c] {
+					box = "[x] "
+				}
+			}
+			crit = append(crit, t.bodyIndent+"- "+box+c)
+		}
+		groups = append(groups, crit)
+	}
+	if s.Notes != "" {
+		groups = append(groups, t.noteBlock(s.Notes))
+	}
+	for _, g := range groups {
+		// Headings get paragraphs; list items stay tight.
+		if t.heading {
+			out = append(out, "")
+		}
+		out = append(out, g...)
+	}
+	return out
+}
+
+func indentWidth(line string) int {
+	w := 0
+	for _, r := range line {
+		switch r {
+		case ' ':
+			w++
+		case '\t':
+			w += 4
+		default:
+			return w
+		}
+	}
+	return w
+}
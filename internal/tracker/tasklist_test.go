@@ -0,0 +1,95 @@
+package tracker
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zhrkvl/ralph-go/internal/prd"
+)
+
+func TestTaskListUpdateConflict(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "TODO.md")
+	if err := os.WriteFile(path, []byte("- [ ] US-001: First\n- [ ] US-002: Second\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tr, err := New("markdown", Options{ProjectDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Someone else edits the file every time the update is being made.
+	edits := 0
+	_, err = tr.(*TaskList).Update(func(p *prd.PRD) error {
+		edits++
+		other := "- [ ] US-001: First\n- [ ] US-002: Second\n" + strings.Repeat("- [ ] Extra\n", edits)
+		if err := os.WriteFile(path, []byte(other), 0o644); err != nil {
+			return err
+		}
+		s, err := p.Story("US-001")
+		if err != nil {
+			return err
+		}
+		s.Passes = true
+		return nil
+	})
+	var conflict *prd.ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("err = %v, want a *prd.ConflictError", err)
+	}
+	if len(conflict.Conflicts) != 1 || conflict.Conflicts[0] != "US-001" {
+		t.Errorf("conflicts = %v, want [US-001]", conflict.Conflicts)
+	}
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "[x]") {
+		t.Errorf("the other edit was overwritten:\n%s", data)
+	}
+
+	// Without interference it goes in.
+	if err := tr.MarkDone("US-002"); err != nil {
+		t.Fatal(err)
+	}
+	data, _ = os.ReadFile(path)
+	if !strings.Contains(string(data), "- [x] US-002: Second") {
+		t.Errorf("US-002 not ticked:\n%s", data)
+	}
+}
+
+func TestTaskListWritesPositionalIDs(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "TODO.md")
+	in := "# Demo\n\n- [ ] First\n- [ ] Second\n  - Works\n- [ ] US-007: Third\n"
+	if err := os.WriteFile(path, []byte(in), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tr, err := New("markdown", Options{ProjectDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.AddNote("T-2", "Check the edge cases"); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	want := "# Demo\n\n- [ ] T-1: First\n- [ ] T-2: Second\n  - Works\n  > Check the edge cases\n- [ ] US-007: Third\n"
+	if string(data) != want {
+		t.Fatalf("after the first write:\n%s\nwant:\n%s", data, want)
+	}
+
+	// A story added in front no longer renumbers the others.
+	if err := os.WriteFile(path, []byte(strings.Replace(want, "- [ ] T-1", "- [ ] Zeroth\n- [ ] T-1", 1)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.MarkDone("T-2"); err != nil {
+		t.Fatal(err)
+	}
+	s, err := tr.Get("T-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Title != "Second" || !s.Passes {
+		t.Errorf("T-2 = %+v, want Second, passing", s)
+	}
+}
@@ -0,0 +1,124 @@
+// Package tracker abstracts where the loop gets its user stories from.
+// The PRD file next to the prompt is the built-in source; other task
+// backends register themselves under a name chosen with the tracker key
+// in config.toml.
+package tracker
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/zhrkvl/ralph-go/internal/prd"
+)
+
+// Tracker is a source of user stories. Stories are always handed out in
+// PRD form so the views, progress bar and completion logic work the same
+// for every backend.
+//
+// Methods may be called from several goroutines and may block on I/O;
+// the TUI only calls them from tea.Cmds.
+type Tracker interface {
+	// Name returns the name the tracker is registered under.
+	Name() string
+
+	// List returns every story, done or not.
+	List() (*prd.PRD, error)
+
+	// Get returns the story with the given ID.
+	Get(id string) (*prd.UserStory, error)
+
+	// Next returns the story the agent should work on next, or nil if
+	// every story passes.
+	Next() (*prd.UserStory, error)
+
+	// MarkDone records that a story passes.
+	MarkDone(id string) error
+
+	// AddNote appends a note to a story.
+	AddNote(id, note string) error
+
+	// Path returns the local file the stories are kept in, or "" if the
+	// tracker has none. The file is watched for changes and opened by
+	// the edit keys.
+	Path() string
+}
+
+// Editor is implemented by trackers whose stories can be edited freely
+// (added, deleted, reordered) from the Stories view.
+type Editor interface {
+	// Update applies fn to the latest stories and saves the result.
+	Update(fn func(*prd.PRD) error) (*prd.PRD, error)
+}
+
+// Completer is implemented by trackers that record how a story was
+// finished, e.g. as an issue comment. The loop calls Complete instead of
+// MarkDone when it is available.
+type Completer interface {
+	Complete(id, summary string) error
+}
+
+// Validator is implemented by trackers that can check their source for
+// problems before the loop starts.
+type Validator interface {
+	Validate() ([]prd.Issue, error)
+}
+
+// Options configures a new tracker.
+type Options struct {
+	RalphDir   string
+	ProjectDir string
+
+	// Settings holds tracker-specific options from config.toml's
+	// [trackerOptions] table (see config.TrackerOptionsFor).
+	Settings map[string]any
+}
+
+// Factory creates a tracker.
+type Factory func(opts Options) (Tracker, error)
+
+var (
+	mu        sync.Mutex
+	factories = map[string]Factory{}
+)
+
+// Register makes a tracker available under name. It panics if the name
+// is taken.
+func Register(name string, f Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, dup := factories[name]; dup {
+		panic("tracker: Register called twice for " + name)
+	}
+	factories[name] = f
+}
+
+// Names lists the registered trackers in alphabetical order.
+func Names() []string {
+	mu.Lock()
+	defer mu.Unlock()
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New creates the tracker registered under name.
+func New(name string, opts Options) (Tracker, error) {
+	mu.Lock()
+	f, ok := factories[name]
+	mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("invalid tracker '%s'. Must be one of: %s", name, strings.Join(Names(), ", "))
+	}
+	return f(opts)
+}
+
+// settingString returns a string-valued setting, or "" if absent.
+func settingString(settings map[string]any, key string) string {
+	v, _ := settings[key].(string)
+	return v
+}
@@ -0,0 +1,164 @@
+package tui
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/zhrkvl/ralph-go/internal/api"
+)
+
+// apiCallMsg runs fn inside Update, where the model may be read and
+// changed, and sends the result back to the waiting HTTP handler.
+type apiCallMsg struct {
+	fn    func(m *Model) (any, tea.Cmd, error)
+	reply chan apiResult
+}
+
+type apiResult struct {
+	v   any
+	err error
+}
+
+// controller implements api.Controller by sending calls to the program.
+type controller struct {
+	p *tea.Program
+}
+
+func (c controller) call(fn func(m *Model) (any, tea.Cmd, error)) (any, error) {
+	reply := make(chan apiResult, 1)
+	go c.p.Send(apiCallMsg{fn: fn, reply: reply})
+	select {
+	case r := <-reply:
+		return r.v, r.err
+	case <-time.After(5 * time.Second):
+		return nil, errors.New("ralph is not responding")
+	}
+}
+
+func (c controller) Status() (any, error) {
+	return c.call(func(m *Model) (any, tea.Cmd, error) {
+		v, err := json.Marshal(m.apiStatus())
+		return json.RawMessage(v), nil, err
+	})
+}
+
+func (c controller) Stories() (any, error) {
+	return c.call(func(m *Model) (any, tea.Cmd, error) {
+		v, err := json.Marshal(m.prd)
+		return json.RawMessage(v), nil, err
+	})
+}
+
+func (c controller) Control(a api.Action, note string) (string, error) {
+	v, err := c.call(func(m *Model) (any, tea.Cmd, error) {
+		msg, cmd, err := m.control(a, note)
+		return msg, cmd, err
+	})
+	msg, _ := v.(string)
+	return msg, err
+}
+
+func (m *Model) apiStatus() api.Status {
+	st := api.Status{
+		Session:         m.sess,
+		State:           m.loopState(),
+		Iteration:       m.iteration,
+		MaxIterations:   m.maxIterations,
+		BlockedReason:   m.blockedReason,
+		StepMode:        m.stepMode,
+		PendingGuidance: m.pendingGuidance,
+	}
+	if m.agentRunning || m.gate != nil {
+		st.CurrentStory = &api.StoryRef{ID: m.iterStoryID, Title: m.iterStoryTitle}
+	} else if m.prd != nil {
+		if s := m.prd.CurrentStory(); s != nil {
+			st.CurrentStory = &api.StoryRef{ID: s.ID, Title: s.Title}
+		}
+	}
+	return st
+}
+
+// loopState describes what the loop is doing in one word.
+func (m *Model) loopState() string {
+	switch {
+	case m.agentRunning && m.agentPaused:
+		return "paused"
+	case m.agentRunning:
+		return "running"
+	case m.gate != nil:
+		return "waiting"
+	case m.blocked:
+		return "blocked"
+	case m.reviewing:
+		return "reviewing"
+	case m.sessionStatus != "running":
+		return m.sessionStatus
+	}
+	return "idle"
+}
+
+// control carries out an API control request the way the matching key
+// would.
+func (m *Model) control(a api.Action, note string) (string, tea.Cmd, error) {
+	if m.replay != nil && a != api.Stop {
+		return "", nil, fmt.Errorf("%w: replaying a recorded iteration", api.ErrConflict)
+	}
+	switch a {
+	case api.Pause:
+		if !m.agentRunning {
+			return "", nil, fmt.Errorf("%w: no agent is running", api.ErrConflict)
+		}
+		if m.agentPaused {
+			return "", nil, fmt.Errorf("%w: the agent is already paused", api.ErrConflict)
+		}
+		m.togglePause()
+		if !m.agentPaused {
+			return "", nil, errors.New("pausing the agent failed")
+		}
+		return "agent paused", nil, nil
+
+	case api.Resume:
+		switch {
+		case m.agentRunning && m.agentPaused:
+			m.togglePause()
+			if m.agentPaused {
+				return "", nil, errors.New("resuming the agent failed")
+			}
+			return "agent resumed", nil, nil
+		case m.gate != nil:
+			m.appendOutput(accentStyle.Render("✓ Approved"))
+			return "iteration approved", m.closeGate(), nil
+		case m.blocked && !m.agentRunning, m.reviewing:
+			return "loop continued", m.continueLoop(), nil
+		}
+		return "", nil, fmt.Errorf("%w: nothing is paused or waiting", api.ErrConflict)
+
+	case api.Skip:
+		if !m.agentRunning {
+			return "", nil, fmt.Errorf("%w: no agent is running", api.ErrConflict)
+		}
+		m.killAgent()
+		m.appendOutput(warnStyle.Render("Skipping iteration..."))
+		return fmt.Sprintf("skipping iteration %d", m.iteration), nil, nil
+
+	case api.Stop:
+		m.killAgent()
+		if m.sessionStatus == "running" || m.sessionStatus == "blocked" {
+			m.sessionStatus = "interrupted"
+		}
+		m.quitting = true
+		m.saveState()
+		return "stopping", tea.Quit, nil
+
+	case api.Note:
+		m.addGuidance(note)
+		if m.guidanceMode == "progress" {
+			return "guidance added to progress.txt", nil, nil
+		}
+		return "guidance queued for the next iteration", nil, nil
+	}
+	return "", nil, fmt.Errorf("unknown action %q", a)
+}
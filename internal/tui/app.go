@@ -3,15 +3,28 @@ package tui
 import (
 	"context"
 	"fmt"
+	"io"
+	"os"
+	"os/signal"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/zhrkvl/ralph-go/internal/agent"
+	"github.com/zhrkvl/ralph-go/internal/api"
+	"github.com/zhrkvl/ralph-go/internal/git"
+	"github.com/zhrkvl/ralph-go/internal/hooks"
+	"github.com/zhrkvl/ralph-go/internal/notify"
 	"github.com/zhrkvl/ralph-go/internal/prd"
 	"github.com/zhrkvl/ralph-go/internal/session"
+	"github.com/zhrkvl/ralph-go/internal/tracker"
+	"github.com/zhrkvl/ralph-go/internal/watch"
+	"github.com/zhrkvl/ralph-go/internal/webhook"
 )
 
 type View int
@@ -29,20 +42,30 @@ const maxOutputLines = 500
 
 // Messages
 
-type agentOutputMsg struct{ line string }
+type agentOutputMsg struct{ line agent.Line }
 
 type agentStartedMsg struct {
-	outputCh <-chan string
-	agent    agent.Agent
-	iterLog  *session.IterationLog
+	iteration  int
+	storyID    string
+	storyTitle string
+	baseRev    string // git HEAD when the iteration started
+	guidance   int    // number of pending guidance notes in the prompt
+	outputCh   <-chan agent.Line
+	agent      agent.Agent
+	iterLog    *session.IterationLog
+	cancel     context.CancelFunc
 }
 
 type agentDoneMsg struct {
 	completed bool
+	iteration int    // set when the agent failed to start
 	errMsg    string // non-empty if agent failed to start
 }
 
-type prdReloadMsg struct{ p *prd.PRD }
+type prdReloadMsg struct {
+	p   *prd.PRD
+	err error
+}
 type tickMsg time.Time
 type iterationSleepDoneMsg struct{}
 
@@ -56,11 +79,19 @@ type Model struct {
 
 	// Data
 	prd        *prd.PRD
-	prdPath    string
+	tracker    tracker.Tracker
+	prdPath    string // the tracker's file, "" if it has none
+	prdWarning string // last PRD reload error, shown until a reload succeeds
 	ralphDir   string
 	projectDir string
 	agentName  string
+	profile    string
+	hooks      *hooks.Runner
+	webhooks   *webhook.Dispatcher
+	notifier   *notify.Notifier
+	server     *api.Server
 	model      string
+	agentOpts  map[string]any
 	archives   []session.ArchiveEntry
 
 	// Agent loop
@@ -70,10 +101,33 @@ type Model struct {
 	iteration      int
 	maxIterations  int
 	outputLines    []string
-	sessionStatus  string // running, completed, failed, interrupted
-	outputCh       <-chan string
+	sessionStatus  string // running, blocked, completed, failed, interrupted
+	outputCh       <-chan agent.Line
 	cancelAgent    context.CancelFunc
 
+	// Agent signals
+	signals         agent.Signals
+	iterCompleted   bool     // completion signal seen this iteration
+	iterAborted     bool     // abort signal seen this iteration
+	iterKilled      bool     // agent killed by ralph (skip, abort, quit)
+	iterStoriesDone []string // story-done signals seen this iteration
+	blocked         bool     // loop held after a blocked signal
+	blockedReason   string
+
+	// Step mode: hold the loop after each iteration for approval
+	stepMode       bool
+	gate           *iterationGate
+	iterStoryID    string
+	iterStoryTitle string
+	iterBaseRev    string
+	iterCost       float64
+
+	// Human guidance for the agent
+	noteInput       textinput.Model
+	noting          bool
+	guidanceMode    string // prompt or progress
+	pendingGuidance []string
+
 	// Viewport for agent output
 	viewport       viewport.Model
 	showTimestamps bool
@@ -82,6 +136,18 @@ type Model struct {
 	storyCursor   int
 	historyCursor int
 
+	// PRD editing in the Stories view
+	storyInput      textinput.Model
+	addingStory     bool
+	confirmDeleteID string
+	storiesMsg      string // result of the last edit
+
+	// File watching; nil falls back to polling the PRD on every tick
+	watcher           *watch.Watcher
+	storyChanges      map[string]storyChange
+	lastChangeID      string
+	progressUpdatedAt time.Time
+
 	// Detail viewport (for story/history detail views)
 	detailViewport viewport.Model
 
@@ -89,46 +155,109 @@ type Model struct {
 	sess    *session.Session
 	iterLog *session.IterationLog
 
+	// Replay mode: play back a recorded stream instead of running an agent
+	replay      []agent.ReplayLine
+	replaySpeed float64
+
+	// Review mode: the loop waits in the Stories view until the user
+	// starts it, e.g. after `ralph plan`
+	reviewing bool
+
+	// Headless: no terminal; the program ends with the loop and output
+	// goes to log
+	headless bool
+	log      io.Writer
+
 	quitting bool
 }
 
 type Options struct {
 	PRD           *prd.PRD
-	PRDPath       string
+	Tracker       tracker.Tracker
 	RalphDir      string
 	ProjectDir    string
 	AgentName     string
+	Profile       string // config profile, shown in the header
+	Hooks         *hooks.Runner
+	Webhooks      *webhook.Dispatcher
+	Notifier      *notify.Notifier
+	Server        *api.Server // HTTP API, served once the program runs
 	Model         string
+	AgentOptions  map[string]any
 	MaxIterations int
 	Session       *session.Session
+	StepMode      bool
+	Guidance      string // "prompt" (default) or "progress"
+
+	// Replay, when non-nil, plays back a recorded raw stream for a single
+	// iteration instead of launching AgentName.
+	Replay      []agent.ReplayLine
+	ReplaySpeed float64
+
+	// Review opens the Stories view and holds the loop until the user
+	// presses c.
+	Review bool
+
+	// Headless runs the loop without a terminal, for `ralph --detach`:
+	// it is controlled through Server only, its output is written to Log
+	// as plain text, and the program exits once the loop is over.
+	Headless bool
+	Log      io.Writer
 }
 
 func NewModel(opts Options) Model {
+	view := viewDashboard
+	if opts.Review {
+		view = viewStories
+	}
+	var pending []string
+	if opts.Session != nil {
+		pending = append(pending, opts.Session.PendingGuidance...)
+	}
 	return Model{
-		activeView:    viewDashboard,
+		activeView:    view,
 		prd:           opts.PRD,
-		prdPath:       opts.PRDPath,
+		tracker:       opts.Tracker,
+		prdPath:       trackerPath(opts.Tracker),
 		ralphDir:      opts.RalphDir,
 		projectDir:    opts.ProjectDir,
 		agentName:     opts.AgentName,
+		profile:       opts.Profile,
+		hooks:         opts.Hooks,
+		webhooks:      opts.Webhooks,
+		notifier:      opts.Notifier,
+		server:        opts.Server,
 		model:         opts.Model,
+		agentOpts:     opts.AgentOptions,
+		signals:       agent.SignalsFromSettings(opts.AgentOptions),
 		maxIterations: opts.MaxIterations,
+		stepMode:      opts.StepMode,
+		guidanceMode:  opts.Guidance,
+		noteInput:     newNoteInput(),
+		storyInput:    newStoryInput(),
 		iteration:     0,
 		sessionStatus: "running",
 		outputLines:   make([]string, 0, maxOutputLines),
 		archives:       loadArchives(opts.RalphDir),
 		sess:           opts.Session,
+		pendingGuidance: pending,
 		viewport:       viewport.New(80, 20),
 		detailViewport: viewport.New(80, 20),
 		showTimestamps: true,
+		replay:         opts.Replay,
+		replaySpeed:    opts.ReplaySpeed,
+		reviewing:      opts.Review,
+		headless:       opts.Headless,
+		log:            opts.Log,
 	}
 }
 
 func (m Model) Init() tea.Cmd {
-	return tea.Batch(
-		m.startAgentCmd(),
-		tickCmd(),
-	)
+	cmds := []tea.Cmd{tickCmd(), waitForFileChange(m.watcher)}
+	if !m.reviewing {
+		cmds = append(cmds, m.startAgentCmd())
+	}
+	return tea.Batch(cmds...)
 }
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -146,64 +275,117 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.handleKeyMsg(msg)
 
 	case agentStartedMsg:
+		m.iteration = msg.iteration
+		m.cancelAgent = msg.cancel
 		m.outputCh = msg.outputCh
 		m.currentAgent = msg.agent
 		m.iterLog = msg.iterLog
+		m.iterStoryID = msg.storyID
+		m.iterStoryTitle = msg.storyTitle
+		m.iterBaseRev = msg.baseRev
+		m.iterCost = 0
 		m.agentRunning = true
 		m.agentPaused = false
+		m.resetSignals()
 		m.appendOutput(fmt.Sprintf(
 			"%s  %s %d / %d",
 			dimStyle.Render(strings.Repeat("═", 50)),
 			titleStyle.Render("Iteration"),
 			m.iteration, m.maxIterations,
 		))
+		m.guidanceDelivered(msg.guidance)
+		// Keep session.json current for `ralph status`
+		if m.sess != nil && m.replay == nil {
+			m.sess.ActiveTaskIDs = []string{m.iterStoryID}
+			m.saveState()
+		}
 		return m, waitForOutput(m.outputCh)
 
 	case agentOutputMsg:
-		m.appendOutput(msg.line)
+		m.appendOutput(msg.line.Text)
+		m.server.Publish("output", api.Output{Iteration: m.iteration, Text: msg.line.Text})
+		if msg.line.CostUSD > 0 {
+			m.iterCost = msg.line.CostUSD
+		}
 		if m.iterLog != nil {
-			m.iterLog.WriteLine(msg.line)
+			m.iterLog.WriteLine(msg.line.Text)
 		}
-		// Check for completion signal in this line
-		if strings.Contains(msg.line, "<promise>COMPLETE</promise>") {
-			// Don't wait for more output — mark completed
-			// The channel will close naturally
+		if cmd := m.handleSignals(msg.line); cmd != nil {
+			return m, tea.Batch(cmd, waitForOutput(m.outputCh))
 		}
 		return m, waitForOutput(m.outputCh)
 
 	case agentDoneMsg:
+		ran := m.agentRunning
+		var exitErr error
+		if m.currentAgent != nil {
+			// The output channel is closed, so the process has exited
+			// and Wait returns at once.
+			_, exitErr = m.currentAgent.Wait()
+		}
 		m.agentRunning = false
 		m.agentPaused = false
 		m.currentAgent = nil
+		if m.sess != nil && ran {
+			m.sess.CostUSD += m.iterCost
+		}
+		if msg.iteration > m.iteration {
+			m.iteration = msg.iteration
+		}
 
 		// Show error if agent failed to start
 		if msg.errMsg != "" {
 			m.appendOutput(errorStyle.Render("Agent error: " + msg.errMsg))
 		}
 
-		// Check accumulated output for completion signal
-		completed := msg.completed
-		if !completed {
-			for _, line := range m.outputLines {
-				if strings.Contains(line, "<promise>COMPLETE</promise>") {
-					completed = true
-					break
-				}
+		completed := msg.completed || m.iterCompleted
+
+		// Hooks run once the branches below have settled the session
+		// status.
+		crash := msg.errMsg
+		if crash == "" && exitErr != nil && !m.iterKilled && !m.iterAborted {
+			crash = exitErr.Error()
+		}
+		done := func(cmd tea.Cmd) (tea.Model, tea.Cmd) {
+			return m, tea.Batch(m.iterationEndHooks(ran, completed, crash), cmd)
+		}
+		// The loop is over; without a terminal there is nobody to look
+		// at the result, so exit once the hooks have run.
+		finished := func() (tea.Model, tea.Cmd) {
+			if !m.headless {
+				return done(nil)
 			}
+			m.quitting = true
+			return m, tea.Sequence(m.iterationEndHooks(ran, completed, crash), tea.Quit)
 		}
 
 		// Close iteration log
 		if m.iterLog != nil {
-			m.iterLog.Close(completed, completed)
+			m.iterLog.Close(completed, m.iterCompleted)
 			m.iterLog = nil
 		}
 
+		if m.replay != nil {
+			m.sessionStatus = "completed"
+			m.appendOutput("")
+			m.appendOutput(accentStyle.Render("Replay finished."))
+			return m, nil
+		}
+
+		if m.iterAborted {
+			m.sessionStatus = "failed"
+			m.appendOutput("")
+			m.appendOutput(errorStyle.Render("Session aborted by agent."))
+			m.saveState()
+			return finished()
+		}
+
 		if completed {
 			m.sessionStatus = "completed"
 			m.appendOutput("")
 			m.appendOutput(accentStyle.Render("All tasks completed!"))
 			m.saveState()
-			return m, nil
+			return finished()
 		}
 
 		if m.iteration >= m.maxIterations {
@@ -212,30 +394,114 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.appendOutput(errorStyle.Render(fmt.Sprintf(
 				"Max iterations (%d) reached without completion.", m.maxIterations)))
 			m.saveState()
-			return m, nil
+			return finished()
+		}
+
+		if m.blocked {
+			m.sessionStatus = "blocked"
+			m.appendOutput(warnStyle.Render("Loop paused: agent needs a human. Press c to continue."))
+			m.saveState()
+			return done(nil)
+		}
+
+		if m.stepMode && m.replay == nil {
+			return done(m.openGate())
 		}
 
 		// Sleep 2s then start next iteration (matching ralph.sh)
 		m.appendOutput(dimStyle.Render("Iteration complete. Next in 2s..."))
-		return m, tea.Tick(2*time.Second, func(_ time.Time) tea.Msg {
+		return done(tea.Tick(2*time.Second, func(_ time.Time) tea.Msg {
 			return iterationSleepDoneMsg{}
-		})
+		}))
 
 	case iterationSleepDoneMsg:
 		return m, m.startAgentCmd()
 
+	case iterationVetoedMsg:
+		m.blocked = true
+		m.blockedReason = msg.err.Error()
+		m.sessionStatus = "blocked"
+		m.appendOutput(warnStyle.Render("⚠ Iteration vetoed: " + msg.err.Error()))
+		m.appendOutput(warnStyle.Render("Loop paused. Press c to try again."))
+		m.saveState()
+		return m, m.blockedHooks()
+
+	case hookFailedMsg:
+		m.appendOutput(warnStyle.Render("⚠ " + msg.err.Error()))
+		return m, nil
+
+	case apiCallMsg:
+		v, cmd, err := msg.fn(&m)
+		msg.reply <- apiResult{v, err}
+		return m, cmd
+
+	case webhookFailedMsg:
+		m.appendOutput(warnStyle.Render("Webhook failed: " + msg.err.Error()))
+		return m, nil
+
+	case gateSummaryMsg:
+		if m.gate != nil {
+			m.gate.loading = false
+			m.gate.passed = msg.passed
+			m.gate.diffStat = msg.diffStat
+			if msg.err != nil {
+				m.gate.diffErr = msg.err.Error()
+			}
+		}
+		return m, nil
+
+	case storyEditedMsg:
+		return m, m.applyStoryEdit(msg)
+
+	case storiesUpdatedMsg:
+		m.applyStoriesUpdate(msg)
+		return m, nil
+
+	case gateUpdatedMsg:
+		return m, m.applyGateUpdate(msg)
+
+	case prdEditedMsg:
+		if msg.err != nil {
+			m.appendOutput(errorStyle.Render("Editor failed: " + msg.err.Error()))
+		}
+		if m.gate != nil {
+			return m, tea.Batch(reloadPRD(m.tracker), gateSummaryCmd(m.tracker, m.projectDir, m.gate.storyID, m.iterBaseRev))
+		}
+		return m, reloadPRD(m.tracker)
+
 	case prdReloadMsg:
+		if msg.err != nil {
+			m.prdWarning = msg.err.Error()
+			return m, nil
+		}
+		m.prdWarning = ""
+		var cmd tea.Cmd
 		if msg.p != nil {
+			passed := m.noteStoryChanges(m.prd, msg.p)
 			m.prd = msg.p
 			if m.sess != nil {
 				m.sess.TasksCompleted = msg.p.CompletedCount()
 			}
+			cmd = m.storyPassedHooks(passed)
 		}
-		return m, nil
+		return m, cmd
+
+	case storyMarkedMsg:
+		if msg.err != nil {
+			m.appendOutput(errorStyle.Render(fmt.Sprintf("Could not mark %s as passing: %v", msg.id, msg.err)))
+			return m, nil
+		}
+		return m, reloadPRD(m.tracker)
+
+	case fileChangedMsg:
+		return m, m.handleFileChanged(msg)
 
 	case tickMsg:
 		var cmds []tea.Cmd
-		cmds = append(cmds, tickCmd(), reloadPRD(m.prdPath))
+		cmds = append(cmds, tickCmd())
+		if m.watcher == nil {
+			cmds = append(cmds, reloadPRD(m.tracker))
+		}
 		if m.sess != nil {
 			m.sess.UpdatedAt = time.Now().UTC()
 			m.sess.CurrentIteration = m.iteration
@@ -249,6 +515,16 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m *Model) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.noting {
+		return m.handleNoteKey(msg)
+	}
+	if m.addingStory {
+		return m.handleAddStoryKey(msg)
+	}
+	if m.confirmDeleteID != "" {
+		return m.handleDeleteConfirmKey(msg)
+	}
+
 	// Confirm quit dialog
 	if m.activeView == viewConfirmQuit {
 		switch msg.String() {
@@ -264,6 +540,17 @@ func (m *Model) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, nil
 	}
 
+	if m.gate != nil && m.activeView == viewDashboard {
+		if handled, cmd := m.handleGateKey(msg); handled {
+			return m, cmd
+		}
+	}
+	if m.activeView == viewStories && m.replay == nil {
+		if handled, cmd := m.handleStoryEditKey(msg); handled {
+			return m, cmd
+		}
+	}
+
 	switch {
 	case key.Matches(msg, keys.Quit):
 		if m.agentRunning {
@@ -285,6 +572,19 @@ func (m *Model) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 		return m, nil
 
+	case key.Matches(msg, keys.Continue):
+		return m, m.continueLoop()
+
+	case key.Matches(msg, keys.Note):
+		if m.replay != nil {
+			return m, nil
+		}
+		return m, m.startNote()
+
+	case key.Matches(msg, keys.StepMode):
+		m.toggleStepMode()
+		return m, nil
+
 	case key.Matches(msg, keys.Skip):
 		if m.agentRunning {
 			m.killAgent()
@@ -416,11 +716,14 @@ func (m Model) View() string {
 		content = renderDashboard(&m) + renderConfirmQuit(m.width)
 	}
 
-	statusBar := renderStatusBar(m.activeView, m.agentRunning, m.width)
+	statusBar := renderStatusBar(&m)
 	return content + "\n" + statusBar
 }
 
 func (m *Model) appendOutput(line string) {
+	if m.log != nil {
+		fmt.Fprintln(m.log, stripAnsi(line))
+	}
 	m.outputLines = append(m.outputLines, line)
 	if len(m.outputLines) > maxOutputLines {
 		m.outputLines = m.outputLines[len(m.outputLines)-maxOutputLines:]
@@ -446,6 +749,7 @@ func (m *Model) togglePause() {
 }
 
 func (m *Model) killAgent() {
+	m.iterKilled = true
 	if m.cancelAgent != nil {
 		m.cancelAgent()
 		m.cancelAgent = nil
@@ -467,50 +771,131 @@ func (m *Model) saveState() {
 }
 
 // startAgentCmd returns a Cmd that starts the next agent iteration.
-// It increments the iteration counter and launches the subprocess.
-// The result is an agentStartedMsg containing the output channel.
+// The Cmd launches the subprocess and reports the new iteration number and
+// output channel in an agentStartedMsg; it does not mutate the model, so it
+// is safe to call from Init and from value-receiver Update paths.
 func (m *Model) startAgentCmd() tea.Cmd {
-	m.iteration++
-	iter := m.iteration
+	iter := m.iteration + 1
 	agentName := m.agentName
-	model := m.model
-	ralphDir := m.ralphDir
 	projectDir := m.projectDir
+	trk := m.tracker
+	current := m.prd
+	doneMarker := m.signals.StoryDone
+	em, start := m.emitter(), m.hookPayload(hooks.IterationStart)
+	agentOpts := agent.Options{
+		RalphDir:   m.ralphDir,
+		ProjectDir: m.projectDir,
+		Model:      m.model,
+		Settings:   m.agentOpts,
+		Guidance:   append([]string(nil), m.pendingGuidance...),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
 
-	taskID := "unknown"
-	taskTitle := "unknown"
-	if m.prd != nil {
-		if cs := m.prd.CurrentStory(); cs != nil {
+	if m.replay != nil {
+		a := agent.NewReplay(agentName, m.replay, m.replaySpeed)
+		return func() tea.Msg {
+			ch, err := a.Start(ctx)
+			if err != nil {
+				cancel()
+				return agentDoneMsg{completed: false, iteration: iter, errMsg: err.Error()}
+			}
+			return agentStartedMsg{iteration: iter, outputCh: ch, agent: a, cancel: cancel}
+		}
+	}
+
+	return func() tea.Msg {
+		baseRev := git.Head(projectDir)
+
+		// Ask the tracker so the log is named after the story the agent
+		// is about to pick, not the one from the last reload.
+		var cs *prd.UserStory
+		if trk != nil {
+			next, err := trk.Next()
+			switch {
+			case err != nil && current != nil:
+				cs = current.CurrentStory()
+			case err == nil && next == nil && trk.Path() == "":
+				// The agent only sees one story at a time and cannot
+				// tell that the list is done; the loop has to.
+				cancel()
+				return agentDoneMsg{completed: true}
+			default:
+				cs = next
+			}
+		}
+		taskID := "unknown"
+		taskTitle := "unknown"
+		if cs != nil {
 			taskID = cs.ID
 			taskTitle = cs.Title
 		}
-	}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	m.cancelAgent = cancel
+		agentOpts.Story = cs
+		if cs != nil && trk.Path() == "" {
+			agentOpts.Task = storyBrief(cs, doneMarker)
+		}
 
-	return func() tea.Msg {
+		start.Iteration, start.StoryID, start.StoryTitle = iter, taskID, taskTitle
+		if err := em.hooks.Run(start); err != nil {
+			cancel()
+			return iterationVetoedMsg{err: err}
+		}
+		em.emit(start)
 		iterLog, _ := session.NewIterationLog(projectDir, taskID, taskTitle, agentName)
 
-		a := agent.New(agentName, ralphDir, projectDir, model)
+		a := agent.New(agentName, agentOpts)
+		if iterLog != nil {
+			a.SetRecorder(iterLog)
+		}
 		ch, err := a.Start(ctx)
 		if err != nil {
+			cancel()
 			if iterLog != nil {
 				iterLog.Close(false, false)
 			}
-			return agentDoneMsg{completed: false, errMsg: err.Error()}
+			return agentDoneMsg{completed: false, iteration: iter, errMsg: err.Error()}
 		}
 
-		_ = iter
 		return agentStartedMsg{
-			outputCh: ch,
-			agent:    a,
-			iterLog:  iterLog,
+			iteration:  iter,
+			storyID:    taskID,
+			storyTitle: taskTitle,
+			baseRev:    baseRev,
+			guidance:   len(agentOpts.Guidance),
+			outputCh:   ch,
+			agent:      a,
+			iterLog:    iterLog,
+			cancel:     cancel,
 		}
 	}
 }
 
-func waitForOutput(ch <-chan string) tea.Cmd {
+// storyBrief describes a story for the prompt when the tracker keeps no
+// file the agent could read, e.g. issues.
+func storyBrief(s *prd.UserStory, doneMarker string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Work on %s: %s\n", s.ID, s.Title)
+	if s.Description != "" {
+		b.WriteString("\n" + s.Description + "\n")
+	}
+	if len(s.AcceptanceCriteria) > 0 {
+		b.WriteString("\nAcceptance criteria:\n\n")
+		for _, c := range s.AcceptanceCriteria {
+			b.WriteString("- " + c + "\n")
+		}
+	}
+	if s.Notes != "" {
+		b.WriteString("\nNotes:\n\n" + s.Notes + "\n")
+	}
+	if doneMarker != "" {
+		fmt.Fprintf(&b, "\nThis story is not tracked in a file you can edit. When it is done, print %s on its own line.\n",
+			strings.ReplaceAll(doneMarker, "{id}", s.ID))
+	}
+	return b.String()
+}
+
+func waitForOutput(ch <-chan agent.Line) tea.Cmd {
 	return func() tea.Msg {
 		if ch == nil {
 			return agentDoneMsg{}
@@ -529,20 +914,80 @@ func tickCmd() tea.Cmd {
 	})
 }
 
-func reloadPRD(path string) tea.Cmd {
+// reloadPRD re-reads the stories from the tracker. An error is retried
+// once after a short delay, since a non-atomic writer may be halfway
+// through the file, and only then reported.
+func reloadPRD(t tracker.Tracker) tea.Cmd {
+	if t == nil {
+		return nil
+	}
 	return func() tea.Msg {
-		p, err := prd.Load(path)
+		p, err := t.List()
 		if err != nil {
-			return prdReloadMsg{p: nil}
+			time.Sleep(250 * time.Millisecond)
+			p, err = t.List()
+		}
+		if err != nil {
+			return prdReloadMsg{err: err}
 		}
 		return prdReloadMsg{p: p}
 	}
 }
 
+// trackerPath returns the tracker's local file, or "" without a tracker.
+func trackerPath(t tracker.Tracker) string {
+	if t == nil {
+		return ""
+	}
+	return t.Path()
+}
+
+// terminalOut is stdout with each write made whole, so writers other
+// than the renderer cannot split a frame.
+type terminalOut struct {
+	*os.File
+	mu sync.Mutex
+}
+
+func (t *terminalOut) Write(b []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.File.Write(b)
+}
+
+func (t *terminalOut) WriteString(s string) (int, error) {
+	return t.Write([]byte(s))
+}
+
 // Run starts the TUI program.
 func Run(opts Options) error {
 	m := NewModel(opts)
-	p := tea.NewProgram(m, tea.WithAltScreen())
+	if w, err := watch.New(watchedFiles(trackerPath(opts.Tracker), opts.RalphDir), fileDebounce); err == nil {
+		m.watcher = w
+		defer w.Close()
+	}
+	// Notifications write escape sequences to the terminal from commands;
+	// they share the renderer's writer so they land between frames.
+	out := &terminalOut{File: os.Stdout}
+	opts.Notifier.SetOutput(out)
+	progOpts := []tea.ProgramOption{tea.WithAltScreen(), tea.WithOutput(out)}
+	if opts.Headless {
+		progOpts = []tea.ProgramOption{tea.WithoutRenderer(), tea.WithInput(nil), tea.WithoutSignalHandler()}
+	}
+	p := tea.NewProgram(m, progOpts...)
+	opts.Webhooks.OnError(func(err error) { p.Send(webhookFailedMsg{err: err}) })
+	opts.Server.Serve(controller{p})
+	if opts.Headless {
+		// Stop as POST /stop does, so the session is saved and the
+		// agent killed
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+		defer signal.Stop(sig)
+		go func() {
+			<-sig
+			controller{p}.Control(api.Stop, "")
+		}()
+	}
 	_, err := p.Run()
 	return err
 }
@@ -0,0 +1,481 @@
+package tui
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/zhrkvl/ralph-go/internal/api"
+	"github.com/zhrkvl/ralph-go/internal/hooks"
+	"github.com/zhrkvl/ralph-go/internal/prd"
+)
+
+// AttachOptions configures a TUI attached to a session that runs in
+// another ralph.
+type AttachOptions struct {
+	Client   *api.Client
+	Addr     string // shown in the header
+	ReadOnly bool   // watch only; no control keys
+}
+
+// Messages of the attached TUI
+
+type attachStatusMsg struct {
+	st  *api.Status
+	p   *prd.PRD
+	err error
+}
+
+type attachEventMsg struct{ ev api.Event }
+
+type attachStreamMsg struct{ err error } // the event stream ended
+
+type attachControlMsg struct {
+	msg string
+	err error
+}
+
+type attachTickMsg struct{}
+
+// attachModel shows the dashboard of a remote session from its API and
+// sends the control keys back to it.
+type attachModel struct {
+	client   *api.Client
+	addr     string
+	readOnly bool
+
+	width, height int
+
+	st        *api.Status
+	prd       *prd.PRD
+	connected bool
+	connErr   string
+
+	outputLines    []string
+	viewport       viewport.Model
+	showTimestamps bool
+
+	noteInput   textinput.Model
+	noting      bool
+	confirmStop bool
+	quitting    bool
+}
+
+func (m attachModel) Init() tea.Cmd {
+	return m.pollStatus()
+}
+
+// pollStatus fetches the status and stories of the session.
+func (m *attachModel) pollStatus() tea.Cmd {
+	c := m.client
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		st, err := c.Status(ctx)
+		if err != nil {
+			return attachStatusMsg{err: err}
+		}
+		p, err := c.Stories(ctx)
+		return attachStatusMsg{st: st, p: p, err: err}
+	}
+}
+
+func attachTick() tea.Cmd {
+	return tea.Tick(time.Second, func(time.Time) tea.Msg { return attachTickMsg{} })
+}
+
+func (m *attachModel) control(a api.Action, note string) tea.Cmd {
+	c := m.client
+	return func() tea.Msg {
+		msg, err := c.Control(context.Background(), a, note)
+		return attachControlMsg{msg: msg, err: err}
+	}
+}
+
+func (m attachModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		m.viewport = initViewport(m.width, m.height)
+		updateViewportContent(&m.viewport, m.outputLines, m.showTimestamps)
+		return m, nil
+
+	case tea.KeyMsg:
+		return m.handleKeyMsg(msg)
+
+	case attachStatusMsg:
+		if msg.err != nil {
+			m.connected = false
+			m.connErr = msg.err.Error()
+		} else {
+			m.connected = true
+			m.connErr = ""
+			m.st, m.prd = msg.st, msg.p
+		}
+		return m, attachTick()
+
+	case attachTickMsg:
+		return m, m.pollStatus()
+
+	case attachEventMsg:
+		m.handleEvent(msg.ev)
+		return m, nil
+
+	case attachStreamMsg:
+		if msg.err != nil {
+			m.connErr = msg.err.Error()
+		}
+		return m, nil
+
+	case attachControlMsg:
+		if msg.err != nil {
+			m.appendOutput(warnStyle.Render("⚠ " + msg.err.Error()))
+		} else {
+			m.appendOutput(accentStyle.Render("→ " + msg.msg))
+		}
+		return m, m.pollStatus()
+	}
+	return m, nil
+}
+
+// handleEvent shows an event from the session's stream: agent output as
+// it comes, iteration starts as the banner the session's own TUI shows,
+// and other loop events as one line.
+func (m *attachModel) handleEvent(ev api.Event) {
+	if ev.Name == "output" {
+		var out api.Output
+		if json.Unmarshal(ev.Data, &out) == nil {
+			m.appendOutput(out.Text)
+		}
+		return
+	}
+	var p hooks.Payload
+	if json.Unmarshal(ev.Data, &p) != nil {
+		return
+	}
+	switch p.Event {
+	case hooks.IterationStart:
+		m.appendOutput(fmt.Sprintf(
+			"%s  %s %d / %d",
+			dimStyle.Render(strings.Repeat("═", 50)),
+			titleStyle.Render("Iteration"),
+			p.Iteration, p.MaxIterations,
+		))
+	case hooks.IterationEnd:
+		m.appendOutput(dimStyle.Render(p.Summary()))
+	case hooks.StoryPassed, hooks.Complete:
+		m.appendOutput(accentStyle.Render(p.Summary()))
+	case hooks.StoryFailed, hooks.Blocked:
+		m.appendOutput(warnStyle.Render(p.Summary()))
+	case hooks.AgentCrash, hooks.BudgetExceeded:
+		m.appendOutput(errorStyle.Render(p.Summary()))
+	case hooks.SessionEnd:
+		m.appendOutput("")
+		m.appendOutput(dimStyle.Render(p.Summary()))
+	}
+}
+
+func (m *attachModel) appendOutput(line string) {
+	m.outputLines = append(m.outputLines, line)
+	if len(m.outputLines) > maxOutputLines {
+		m.outputLines = m.outputLines[len(m.outputLines)-maxOutputLines:]
+	}
+	updateViewportContent(&m.viewport, m.outputLines, m.showTimestamps)
+}
+
+func (m *attachModel) state() string {
+	if m.st == nil {
+		return ""
+	}
+	return m.st.State
+}
+
+func (m *attachModel) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.noting {
+		switch msg.Type {
+		case tea.KeyEsc:
+			m.noting = false
+			m.noteInput.Blur()
+			return m, nil
+		case tea.KeyEnter:
+			note := strings.TrimSpace(m.noteInput.Value())
+			m.noting = false
+			m.noteInput.Blur()
+			if note == "" {
+				return m, nil
+			}
+			return m, m.control(api.Note, note)
+		}
+		var cmd tea.Cmd
+		m.noteInput, cmd = m.noteInput.Update(msg)
+		return m, cmd
+	}
+	if m.confirmStop {
+		switch msg.String() {
+		case "y", "Y":
+			m.confirmStop = false
+			return m, m.control(api.Stop, "")
+		case "n", "N", "esc":
+			m.confirmStop = false
+		}
+		return m, nil
+	}
+
+	switch {
+	case key.Matches(msg, keys.Quit):
+		m.quitting = true
+		return m, tea.Quit
+
+	case key.Matches(msg, keys.Timestamps):
+		m.showTimestamps = !m.showTimestamps
+		updateViewportContent(&m.viewport, m.outputLines, m.showTimestamps)
+		return m, nil
+
+	case key.Matches(msg, keys.Up):
+		m.viewport.LineUp(1)
+	case key.Matches(msg, keys.Down):
+		m.viewport.LineDown(1)
+	case key.Matches(msg, keys.PageUp):
+		m.viewport.ViewUp()
+	case key.Matches(msg, keys.PageDown):
+		m.viewport.ViewDown()
+	case key.Matches(msg, keys.HalfPageUp):
+		m.viewport.HalfViewUp()
+	case key.Matches(msg, keys.HalfPageDown):
+		m.viewport.HalfViewDown()
+	}
+	if m.readOnly || !m.connected {
+		return m, nil
+	}
+
+	switch {
+	case key.Matches(msg, keys.Pause):
+		switch m.state() {
+		case "running":
+			return m, m.control(api.Pause, "")
+		case "paused":
+			return m, m.control(api.Resume, "")
+		}
+
+	case key.Matches(msg, keys.Continue):
+		return m, m.control(api.Resume, "")
+
+	case key.Matches(msg, keys.Skip):
+		return m, m.control(api.Skip, "")
+
+	case key.Matches(msg, keys.Note):
+		m.noting = true
+		m.noteInput.Reset()
+		m.noteInput.Width = m.width - len(m.noteInput.Prompt) - 2
+		return m, m.noteInput.Focus()
+
+	case key.Matches(msg, keys.Stop):
+		m.confirmStop = true
+	}
+	return m, nil
+}
+
+func (m attachModel) View() string {
+	if m.quitting {
+		return ""
+	}
+	var b strings.Builder
+	w := m.width
+
+	// Line 1: Ralph | tool | iteration | status | branch
+	agentLabel := "?"
+	iteration := ""
+	if m.st != nil && m.st.Session != nil {
+		agentLabel = m.st.Session.AgentPlugin
+		if m.st.Session.Profile != "" {
+			agentLabel += " " + accentStyle.Render("["+m.st.Session.Profile+"]")
+		}
+	}
+	if m.st != nil {
+		if m.st.StepMode {
+			agentLabel += " " + warnStyle.Render("(step)")
+		}
+		iteration = fmt.Sprintf("Iteration %d/%d", m.st.Iteration, m.st.MaxIterations)
+	}
+	left := fmt.Sprintf("%s %s %s %s %s",
+		titleStyle.Render("Ralph"),
+		dimStyle.Render("|"),
+		agentLabel,
+		dimStyle.Render("|"),
+		iteration,
+	)
+	if m.st != nil && len(m.st.PendingGuidance) > 0 {
+		left += " " + accentStyle.Render(fmt.Sprintf("✎%d", len(m.st.PendingGuidance)))
+	}
+	mode := "attached"
+	if m.readOnly {
+		mode = "attached, read-only"
+	}
+	left += fmt.Sprintf(" %s %s %s", dimStyle.Render("|"), m.renderState(), dimStyle.Render("("+mode+")"))
+	right := ""
+	if m.prd != nil {
+		right = dimStyle.Render(m.prd.BranchName)
+	}
+	gap := w - lipglossWidth(left) - lipglossWidth(right)
+	if gap < 1 {
+		gap = 1
+	}
+	b.WriteString(left + strings.Repeat(" ", gap) + right)
+	b.WriteString("\n")
+
+	// Line 2: Project — N/M stories + progress bar
+	if m.prd != nil {
+		completed := m.prd.CompletedCount()
+		total := m.prd.TotalCount()
+		pct := 0
+		if total > 0 {
+			pct = completed * 100 / total
+		}
+		b.WriteString(fmt.Sprintf("%s %s %d/%d stories %s %d%%",
+			m.prd.Name,
+			dimStyle.Render("—"),
+			completed, total,
+			renderProgressBar(completed, total, w-40),
+			pct,
+		))
+	}
+	b.WriteString("\n")
+	b.WriteString(separator(w))
+	b.WriteString("\n")
+
+	// Current story, and where the session is served
+	current := ""
+	if m.st != nil && m.st.CurrentStory != nil {
+		current = fmt.Sprintf("%s %s %s",
+			accentStyle.Render("▶"),
+			accentStyle.Render(m.st.CurrentStory.ID),
+			m.st.CurrentStory.Title,
+		)
+	}
+	if m.st != nil && m.st.BlockedReason != "" {
+		current += " " + warnStyle.Render("⚠ "+m.st.BlockedReason)
+	}
+	where := dimStyle.Render(m.addr)
+	switch {
+	case m.connected:
+	case m.st != nil:
+		where = warnStyle.Render("⚠ disconnected, ralph is not running; retrying")
+	case m.connErr != "":
+		where = warnStyle.Render("⚠ cannot reach ralph: " + m.connErr)
+	}
+	gap = w - lipglossWidth(current) - lipglossWidth(where)
+	if gap < 1 {
+		gap = 1
+	}
+	b.WriteString(current + strings.Repeat(" ", gap) + where)
+	b.WriteString("\n")
+	b.WriteString(separator(w))
+	b.WriteString("\n")
+
+	b.WriteString(m.viewport.View())
+	b.WriteString("\n")
+	if m.noting {
+		b.WriteString(m.noteInput.View())
+	} else {
+		b.WriteString(separator(w))
+	}
+
+	content := b.String()
+	if m.confirmStop {
+		content += renderCenteredWarning(w, "Stop the session and kill the agent? (y/n)")
+	}
+	return content + "\n" + m.renderStatusBar()
+}
+
+func (m *attachModel) renderState() string {
+	switch s := m.state(); s {
+	case "":
+		return dimStyle.Render("Connecting")
+	case "running":
+		return statusRunning.Render("Running")
+	case "paused":
+		return statusPaused.Render("Paused")
+	case "waiting":
+		return statusPaused.Render("Awaiting approval")
+	case "blocked":
+		return statusPaused.Render("Blocked")
+	case "reviewing":
+		return statusPaused.Render("Review")
+	case "completed":
+		return statusCompleted.Render("Completed")
+	case "failed", "interrupted":
+		return statusFailed.Render(strings.ToUpper(s[:1]) + s[1:])
+	default:
+		return dimStyle.Render("Idle")
+	}
+}
+
+func (m *attachModel) renderStatusBar() string {
+	var hints []string
+	if !m.readOnly && m.connected {
+		switch m.state() {
+		case "running":
+			hints = append(hints, keyHint("p", "pause"), keyHint("s", "skip"))
+		case "paused":
+			hints = append(hints, keyHint("p", "resume"), keyHint("s", "skip"))
+		case "waiting":
+			hints = append(hints, keyHint("c", "approve"))
+		case "blocked", "reviewing":
+			hints = append(hints, keyHint("c", "continue"))
+		}
+		hints = append(hints, keyHint("g", "guide"), keyHint("x", "stop"))
+	}
+	hints = append(hints, keyHint("t", "timestamps"), keyHint("↑↓", "scroll"), keyHint("q", "detach"))
+	return strings.Join(hints, "  ")
+}
+
+// streamEvents follows the session's event stream, reconnecting when it
+// drops, until ctx ends. The first connection replays the events the
+// session still buffers, so the output starts with recent history.
+func streamEvents(ctx context.Context, c *api.Client, p *tea.Program) {
+	lastID := 0
+	for ctx.Err() == nil {
+		err := c.Events(ctx, lastID, func(ev api.Event) {
+			lastID = ev.ID
+			p.Send(attachEventMsg{ev})
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			err = errors.New("event stream closed")
+		}
+		p.Send(attachStreamMsg{err: err})
+		select {
+		case <-ctx.Done():
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
+
+// Attach runs a TUI onto the session served at opts.Client. Detaching
+// leaves the session running.
+func Attach(opts AttachOptions) error {
+	m := attachModel{
+		client:         opts.Client,
+		addr:           opts.Addr,
+		readOnly:       opts.ReadOnly,
+		viewport:       viewport.New(80, 20),
+		showTimestamps: true,
+		noteInput:      newNoteInput(),
+	}
+	p := tea.NewProgram(m, tea.WithAltScreen())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go streamEvents(ctx, opts.Client, p)
+	_, err := p.Run()
+	return err
+}
@@ -3,7 +3,10 @@ package tui
 import "fmt"
 
 func renderConfirmQuit(width int) string {
-	msg := "Agent is running. Quit and kill it? (y/n)"
+	return renderCenteredWarning(width, "Agent is running. Quit and kill it? (y/n)")
+}
+
+func renderCenteredWarning(width int, msg string) string {
 	padding := ""
 	if width > len(msg)+4 {
 		pad := (width - len(msg)) / 2
@@ -3,6 +3,7 @@ package tui
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/viewport"
 )
@@ -13,13 +14,26 @@ func renderDashboard(m *Model) string {
 
 	// Line 1: Ralph | tool | iteration | status | branch
 	statusStr := renderStatus(m)
+	agentLabel := m.agentName
+	if m.profile != "" {
+		agentLabel += " " + accentStyle.Render("["+m.profile+"]")
+	}
+	if m.replay != nil {
+		agentLabel += " " + warnStyle.Render("(replay)")
+	}
+	if m.stepMode {
+		agentLabel += " " + warnStyle.Render("(step)")
+	}
 	left := fmt.Sprintf("%s %s %s %s %s",
 		titleStyle.Render("Ralph"),
 		dimStyle.Render("|"),
-		m.agentName,
+		agentLabel,
 		dimStyle.Render("|"),
 		fmt.Sprintf("Iteration %d/%d", m.iteration, m.maxIterations),
 	)
+	if n := len(m.pendingGuidance); n > 0 {
+		left += " " + accentStyle.Render(fmt.Sprintf("✎%d", n))
+	}
 	right := ""
 	if m.prd != nil {
 		right = dimStyle.Render(m.prd.BranchName)
@@ -53,24 +67,32 @@ func renderDashboard(m *Model) string {
 		)
 		b.WriteString(line)
 	}
+	if m.prdWarning != "" {
+		b.WriteString(" " + warnStyle.Render("⚠ "+m.prdWarning))
+	}
 	b.WriteString("\n")
 
 	// Separator
 	b.WriteString(separator(w))
 	b.WriteString("\n")
 
-	// Current story
+	// Current story, and the latest change seen on disk
 	if m.prd != nil {
+		current := accentStyle.Render("All stories completed!")
 		if cs := m.prd.CurrentStory(); cs != nil {
-			b.WriteString(fmt.Sprintf("%s %s %s (P%d)",
+			current = fmt.Sprintf("%s %s %s (P%d)",
 				accentStyle.Render("▶"),
 				accentStyle.Render(cs.ID),
 				cs.Title,
 				cs.Priority,
-			))
-		} else {
-			b.WriteString(accentStyle.Render("All stories completed!"))
+			)
 		}
+		changes := renderLastChanges(m)
+		gap := w - lipglossWidth(current) - lipglossWidth(changes)
+		if gap < 1 {
+			gap = 1
+		}
+		b.WriteString(current + strings.Repeat(" ", gap) + changes)
 	}
 	b.WriteString("\n")
 
@@ -78,16 +100,39 @@ func renderDashboard(m *Model) string {
 	b.WriteString(separator(w))
 	b.WriteString("\n")
 
-	// Agent output viewport (fill remaining space)
-	b.WriteString(m.viewport.View())
+	// Agent output viewport (fill remaining space), or the iteration
+	// summary while step mode waits for approval
+	if m.gate != nil {
+		b.WriteString(renderGate(m, w, m.viewport.Height))
+	} else {
+		b.WriteString(m.viewport.View())
+	}
 	b.WriteString("\n")
 
-	// Bottom separator
-	b.WriteString(separator(w))
+	// Bottom separator, or the guidance input while it is open
+	if m.noting {
+		b.WriteString(m.noteInput.View())
+	} else {
+		b.WriteString(separator(w))
+	}
 
 	return b.String()
 }
 
+// renderLastChanges shows the most recent story change and progress.txt
+// update, e.g. "US-002 passed 14:03:05 · progress 14:03:06".
+func renderLastChanges(m *Model) string {
+	var parts []string
+	if c, ok := m.storyChanges[m.lastChangeID]; ok {
+		parts = append(parts, m.lastChangeID+" "+renderChange(c, m.recentlyChanged(m.lastChangeID)))
+	}
+	if !m.progressUpdatedAt.IsZero() {
+		parts = append(parts, renderChange(storyChange{what: "progress", at: m.progressUpdatedAt},
+			time.Since(m.progressUpdatedAt) < recentChange))
+	}
+	return strings.Join(parts, dimStyle.Render(" · "))
+}
+
 func renderStatus(m *Model) string {
 	if m.sessionStatus == "completed" {
 		return statusCompleted.Render("Completed")
@@ -95,6 +140,15 @@ func renderStatus(m *Model) string {
 	if m.sessionStatus == "failed" {
 		return statusFailed.Render("Failed")
 	}
+	if m.reviewing {
+		return statusPaused.Render("Review")
+	}
+	if m.blocked && !m.agentRunning {
+		return statusPaused.Render("Blocked")
+	}
+	if m.gate != nil {
+		return statusPaused.Render("Awaiting approval")
+	}
 	if m.agentPaused {
 		return statusPaused.Render("Paused")
 	}
@@ -0,0 +1,234 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/zhrkvl/ralph-go/internal/git"
+	"github.com/zhrkvl/ralph-go/internal/prd"
+	"github.com/zhrkvl/ralph-go/internal/tracker"
+)
+
+// iterationGate holds the loop between iterations in step mode until the
+// user approves, retries, skips the story or edits the PRD.
+type iterationGate struct {
+	iteration  int
+	storyID    string
+	storyTitle string
+	costUSD    float64
+
+	// Filled in asynchronously by gateSummaryCmd
+	loading  bool
+	passed   bool
+	diffStat string
+	diffErr  string
+
+	// saving is set while a retry or skip is being written
+	saving bool
+}
+
+type gateSummaryMsg struct {
+	passed   bool
+	diffStat string
+	err      error
+}
+
+type prdEditedMsg struct{ err error }
+
+// gateUpdatedMsg reports the story update behind a retry or skip.
+type gateUpdatedMsg struct {
+	p      *prd.PRD
+	err    error
+	done   string // shown once the update is in
+	failed string // prefix for the error
+}
+
+// openGate stops the loop after an iteration and starts collecting the
+// iteration summary.
+func (m *Model) openGate() tea.Cmd {
+	m.gate = &iterationGate{
+		iteration:  m.iteration,
+		storyID:    m.iterStoryID,
+		storyTitle: m.iterStoryTitle,
+		costUSD:    m.iterCost,
+		loading:    true,
+	}
+	m.appendOutput(warnStyle.Render("Step mode: waiting for approval (a approve, r retry, x skip story, e edit PRD)"))
+	return gateSummaryCmd(m.tracker, m.projectDir, m.iterStoryID, m.iterBaseRev)
+}
+
+func gateSummaryCmd(t tracker.Tracker, projectDir, storyID, baseRev string) tea.Cmd {
+	return func() tea.Msg {
+		var msg gateSummaryMsg
+		if t != nil {
+			if s, err := t.Get(storyID); err == nil {
+				msg.passed = s.Passes
+			}
+		}
+		msg.diffStat, msg.err = git.DiffStat(projectDir, baseRev)
+		return msg
+	}
+}
+
+// handleGateKey handles the approval keys while a gate is open. Returns
+// false if the key is not a gate action.
+func (m *Model) handleGateKey(msg tea.KeyMsg) (bool, tea.Cmd) {
+	if m.gate.saving && key.Matches(msg, keys.Approve, keys.Retry, keys.SkipStory, keys.EditPRD) {
+		return true, nil
+	}
+	switch {
+	case key.Matches(msg, keys.Approve):
+		m.appendOutput(accentStyle.Render("✓ Approved"))
+		return true, m.closeGate()
+	case key.Matches(msg, keys.Retry):
+		return true, m.retryStory()
+	case key.Matches(msg, keys.SkipStory):
+		return true, m.skipStory()
+	case key.Matches(msg, keys.EditPRD):
+		if m.prdPath == "" {
+			m.appendOutput(warnStyle.Render("The " + m.tracker.Name() + " tracker has no file to edit"))
+			return true, nil
+		}
+		return true, editFileCmd(m.prdPath)
+	}
+	return false, nil
+}
+
+// closeGate resumes the loop with the next iteration. The agent asks the
+// tracker for its story itself, so the reload only refreshes the views.
+func (m *Model) closeGate() tea.Cmd {
+	m.gate = nil
+	return tea.Batch(reloadPRD(m.tracker), m.startAgentCmd())
+}
+
+// retryStory makes the next iteration work on the gated story again by
+// reverting passes if the agent flipped it.
+func (m *Model) retryStory() tea.Cmd {
+	id := m.gate.storyID
+	return m.gateUpdateCmd("↻ Retrying "+id, "Retry failed: ", func(p *prd.PRD) error {
+		s, err := p.Story(id)
+		if err != nil {
+			return err
+		}
+		s.Passes = false
+		return nil
+	})
+}
+
+// skipStory moves the gated story behind every other story so the agent
+// picks something else next, and leaves a note explaining why, in one
+// write.
+func (m *Model) skipStory() tea.Cmd {
+	id := m.gate.storyID
+	iteration := m.gate.iteration
+	return m.gateUpdateCmd("⤼ Skipped "+id, "Skip failed: ", func(p *prd.PRD) error {
+		s, err := p.Story(id)
+		if err != nil {
+			return err
+		}
+		s.Priority = p.MaxPriority() + 1
+		s.AddNote(fmt.Sprintf("Skipped by user after iteration %d.", iteration))
+		return nil
+	})
+}
+
+// gateUpdateCmd writes a retry or skip through the tracker. The gate
+// closes when gateUpdatedMsg reports it in.
+func (m *Model) gateUpdateCmd(done, failed string, fn func(*prd.PRD) error) tea.Cmd {
+	e, err := m.storyEditor()
+	if err != nil {
+		m.appendOutput(errorStyle.Render(failed + err.Error()))
+		return nil
+	}
+	m.gate.saving = true
+	return func() tea.Msg {
+		p, err := e.Update(fn)
+		return gateUpdatedMsg{p: p, err: err, done: done, failed: failed}
+	}
+}
+
+// applyGateUpdate reports a retry or skip and, once it is in, closes the
+// gate unless something else (an API resume) already did.
+func (m *Model) applyGateUpdate(msg gateUpdatedMsg) tea.Cmd {
+	if m.gate != nil {
+		m.gate.saving = false
+	}
+	if msg.err != nil {
+		m.appendOutput(errorStyle.Render(msg.failed + msg.err.Error()))
+		return nil
+	}
+	m.setPRD(msg.p)
+	m.appendOutput(warnStyle.Render(msg.done))
+	if m.gate == nil {
+		return nil
+	}
+	return m.closeGate()
+}
+
+// editFileCmd suspends the TUI and opens path in $VISUAL/$EDITOR.
+func editFileCmd(path string) tea.Cmd {
+	return tea.ExecProcess(editorCommand(path), func(err error) tea.Msg {
+		return prdEditedMsg{err: err}
+	})
+}
+
+// toggleStepMode switches step mode on or off for future iterations.
+func (m *Model) toggleStepMode() {
+	m.stepMode = !m.stepMode
+	if m.stepMode {
+		m.appendOutput(accentStyle.Render("Step mode on: ralph will wait for approval after each iteration"))
+	} else {
+		m.appendOutput(accentStyle.Render("Step mode off"))
+	}
+}
+
+func renderGate(m *Model, width, height int) string {
+	g := m.gate
+	var b strings.Builder
+
+	b.WriteString(titleStyle.Render(fmt.Sprintf("Iteration %d summary", g.iteration)))
+	b.WriteString("\n\n")
+
+	b.WriteString(fmt.Sprintf("%s %s %s", dimStyle.Render("Story:"), accentStyle.Render(g.storyID), g.storyTitle))
+	if !g.loading {
+		if g.passed {
+			b.WriteString("  " + storyCompletedStyle.Render("✓ passes"))
+		} else {
+			b.WriteString("  " + storyOpenStyle.Render("○ not passing"))
+		}
+	}
+	b.WriteString("\n")
+
+	if g.costUSD > 0 {
+		b.WriteString(fmt.Sprintf("%s $%.4f\n", dimStyle.Render("Cost:"), g.costUSD))
+	}
+
+	b.WriteString("\n")
+	b.WriteString(dimStyle.Render("Changes:"))
+	b.WriteString("\n")
+	switch {
+	case g.loading:
+		b.WriteString(dimStyle.Render("  loading..."))
+	case g.diffErr != "":
+		b.WriteString(errorStyle.Render("  " + g.diffErr))
+	case g.diffStat == "":
+		b.WriteString(dimStyle.Render("  no changes"))
+	default:
+		for _, l := range strings.Split(g.diffStat, "\n") {
+			b.WriteString("  " + l + "\n")
+		}
+	}
+	b.WriteString("\n\n")
+
+	b.WriteString(strings.Join([]string{
+		bindingHint(keys.Approve),
+		bindingHint(keys.Retry),
+		bindingHint(keys.SkipStory),
+		bindingHint(keys.EditPRD),
+	}, "  "))
+
+	return lipgloss.NewStyle().Width(width).Height(height).MaxHeight(height).Render(b.String())
+}
@@ -0,0 +1,92 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/zhrkvl/ralph-go/internal/session"
+)
+
+// GuidanceModes lists the values of guidance: notes go into the next
+// prompt or into progress.txt.
+var GuidanceModes = []string{"prompt", "progress"}
+
+func newNoteInput() textinput.Model {
+	ti := textinput.New()
+	ti.Prompt = "Note to agent: "
+	ti.Placeholder = "guidance for the next iteration (Enter to send, Esc to cancel)"
+	ti.CharLimit = 2000
+	return ti
+}
+
+// startNote opens the guidance input on the dashboard.
+func (m *Model) startNote() tea.Cmd {
+	m.noting = true
+	m.activeView = viewDashboard
+	m.noteInput.Reset()
+	m.noteInput.Width = m.width - len(m.noteInput.Prompt) - 2
+	return m.noteInput.Focus()
+}
+
+// handleNoteKey routes keys to the guidance input while it is open.
+func (m *Model) handleNoteKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.noting = false
+		m.noteInput.Blur()
+		return m, nil
+	case tea.KeyEnter:
+		note := strings.TrimSpace(m.noteInput.Value())
+		m.noting = false
+		m.noteInput.Blur()
+		if note != "" {
+			m.addGuidance(note)
+		}
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.noteInput, cmd = m.noteInput.Update(msg)
+	return m, cmd
+}
+
+// addGuidance stores a note for the agent. In "progress" mode it goes into
+// progress.txt right away; otherwise it is queued and injected into the
+// next iteration's prompt.
+func (m *Model) addGuidance(note string) {
+	if m.guidanceMode == "progress" {
+		if err := session.AppendGuidance(m.ralphDir, note); err != nil {
+			m.appendOutput(errorStyle.Render("Saving guidance failed: " + err.Error()))
+			return
+		}
+		m.appendOutput(accentStyle.Render("✎ Guidance added to progress.txt: ") + note)
+		return
+	}
+	m.pendingGuidance = append(m.pendingGuidance, note)
+	if m.sess != nil {
+		// Saved now, so a restart picks the note up
+		m.sess.PendingGuidance = append([]string(nil), m.pendingGuidance...)
+		m.saveState()
+	}
+	m.appendOutput(accentStyle.Render("✎ Guidance queued for the next iteration: ") + note)
+}
+
+// guidanceDelivered drops the first n pending notes once an iteration
+// has started with them in its prompt.
+func (m *Model) guidanceDelivered(n int) {
+	if n == 0 {
+		return
+	}
+	delivered := m.pendingGuidance[:n]
+	m.pendingGuidance = m.pendingGuidance[n:]
+	if m.sess != nil {
+		m.sess.PendingGuidance = append([]string(nil), m.pendingGuidance...)
+	}
+	m.appendOutput(dimStyle.Render(fmt.Sprintf("Delivered %d guidance note(s) in the prompt", n)))
+	if m.iterLog != nil {
+		for _, note := range delivered {
+			m.iterLog.WriteLine("[guidance] " + note)
+		}
+	}
+}
@@ -43,7 +43,7 @@ func renderHistory(m *Model) string {
 		a := m.archives[i]
 		var files []string
 		if a.HasPRD {
-			files = append(files, "prd.json")
+			files = append(files, a.PRDFile)
 		}
 		if a.HasProgress {
 			files = append(files, "progress.txt")
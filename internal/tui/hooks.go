@@ -0,0 +1,179 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/zhrkvl/ralph-go/internal/api"
+	"github.com/zhrkvl/ralph-go/internal/hooks"
+	"github.com/zhrkvl/ralph-go/internal/notify"
+	"github.com/zhrkvl/ralph-go/internal/prd"
+	"github.com/zhrkvl/ralph-go/internal/webhook"
+)
+
+// hookFailedMsg reports hooks or notifications that failed. Those that
+// succeed are silent.
+type hookFailedMsg struct {
+	err error
+}
+
+// webhookFailedMsg reports a delivery that failed after its last retry.
+type webhookFailedMsg struct {
+	err error
+}
+
+// iterationVetoedMsg is sent instead of agentStartedMsg when the
+// iterationStart hook fails; the loop holds as if the agent were blocked.
+type iterationVetoedMsg struct {
+	err error
+}
+
+// emitter delivers loop events to the hooks, webhooks, notifications and
+// API event stream. Its zero value delivers nothing.
+type emitter struct {
+	hooks    *hooks.Runner
+	webhooks *webhook.Dispatcher
+	notifier *notify.Notifier
+	server   *api.Server
+}
+
+func (m *Model) emitter() emitter {
+	return emitter{m.hooks, m.webhooks, m.notifier, m.server}
+}
+
+// active reports whether anything listens for events at all.
+func (e emitter) active() bool {
+	return e.hooks != nil || e.webhooks != nil || e.notifier != nil || e.server != nil
+}
+
+// wants reports whether anything listens for ev.
+func (e emitter) wants(ev hooks.Event) bool {
+	return e.hooks.Has(ev) || e.webhooks != nil || e.notifier.Wants(ev) || e.server != nil
+}
+
+// emit delivers p everywhere but to its hook.
+func (e emitter) emit(p hooks.Payload) error {
+	if p.Time.IsZero() {
+		p.Time = time.Now().UTC()
+	}
+	e.webhooks.Send(p)
+	e.server.Publish(string(p.Event), p)
+	return e.notifier.Notify(p)
+}
+
+// fire emits p and runs its hook.
+func (e emitter) fire(p hooks.Payload) error {
+	return errors.Join(e.emit(p), e.hooks.Run(p))
+}
+
+// hookPayload describes the current iteration for a hook.
+func (m *Model) hookPayload(e hooks.Event) hooks.Payload {
+	p := hooks.Payload{
+		Event:         e,
+		ProjectDir:    m.projectDir,
+		Iteration:     m.iteration,
+		MaxIterations: m.maxIterations,
+		StoryID:       m.iterStoryID,
+		StoryTitle:    m.iterStoryTitle,
+		Status:        m.sessionStatus,
+	}
+	if m.sess != nil {
+		p.SessionID = m.sess.SessionID
+	}
+	return p
+}
+
+// iterationEndHooks runs the hooks for the end of an iteration: agent
+// crash, iteration end, story failed, completion, running out of
+// iterations and the loop being held, in that order. Whether the story
+// failed is asked of the tracker, since the last reload may not have
+// caught the agent's final edit yet.
+func (m *Model) iterationEndHooks(ran, completed bool, crash string) tea.Cmd {
+	em := m.emitter()
+	if !em.active() {
+		return nil
+	}
+	trk := m.tracker
+	base := m.hookPayload(hooks.IterationEnd)
+	blockedReason := m.blockedReason
+	signalled := slices.Contains(m.iterStoriesDone, base.StoryID)
+	budget := !completed && !m.iterAborted && m.iteration >= m.maxIterations
+
+	return func() tea.Msg {
+		var errs []error
+		run := func(e hooks.Event, reason string) {
+			p := base
+			p.Event, p.Reason = e, reason
+			if err := em.fire(p); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if crash != "" {
+			run(hooks.AgentCrash, crash)
+		}
+		if ran {
+			run(hooks.IterationEnd, "")
+			if !completed && !signalled && trk != nil && em.wants(hooks.StoryFailed) {
+				if s, err := trk.Get(base.StoryID); err == nil && !s.Passes {
+					run(hooks.StoryFailed, "")
+				}
+			}
+		}
+		if completed {
+			run(hooks.Complete, "")
+		}
+		if budget {
+			run(hooks.BudgetExceeded, fmt.Sprintf("maxIterations (%d) reached", base.MaxIterations))
+		}
+		if base.Status == "blocked" {
+			run(hooks.Blocked, blockedReason)
+		}
+		if len(errs) == 0 {
+			return nil
+		}
+		return hookFailedMsg{err: errors.Join(errs...)}
+	}
+}
+
+// storyPassedHooks runs the storyPassed hook for each newly passing story.
+func (m *Model) storyPassedHooks(stories []prd.UserStory) tea.Cmd {
+	em := m.emitter()
+	if !em.active() || len(stories) == 0 {
+		return nil
+	}
+	base := m.hookPayload(hooks.StoryPassed)
+	return func() tea.Msg {
+		var errs []error
+		for _, s := range stories {
+			p := base
+			p.StoryID, p.StoryTitle = s.ID, s.Title
+			if err := em.fire(p); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if len(errs) == 0 {
+			return nil
+		}
+		return hookFailedMsg{err: errors.Join(errs...)}
+	}
+}
+
+// blockedHooks runs the blocked hook for a loop held by a vetoed
+// iteration.
+func (m *Model) blockedHooks() tea.Cmd {
+	em := m.emitter()
+	if !em.active() {
+		return nil
+	}
+	p := m.hookPayload(hooks.Blocked)
+	p.Reason = m.blockedReason
+	return func() tea.Msg {
+		if err := em.fire(p); err != nil {
+			return hookFailedMsg{err: err}
+		}
+		return nil
+	}
+}
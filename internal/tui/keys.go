@@ -7,6 +7,23 @@ type keyMap struct {
 	Tab          key.Binding
 	Pause        key.Binding
 	Skip         key.Binding
+	Continue     key.Binding
+	StepMode     key.Binding
+	Note         key.Binding
+	Stop         key.Binding
+	Approve      key.Binding
+	Retry        key.Binding
+	SkipStory    key.Binding
+	EditPRD      key.Binding
+	AddStory     key.Binding
+	EditJSON     key.Binding
+	EditYAML     key.Binding
+	DeleteStory  key.Binding
+	TogglePasses key.Binding
+	RaisePrio    key.Binding
+	LowerPrio    key.Binding
+	MoveUp       key.Binding
+	MoveDown     key.Binding
 	Timestamps   key.Binding
 	Up           key.Binding
 	Down         key.Binding
@@ -35,6 +52,76 @@ var keys = keyMap{
 		key.WithKeys("s"),
 		key.WithHelp("s", "skip"),
 	),
+	Continue: key.NewBinding(
+		key.WithKeys("c"),
+		key.WithHelp("c", "continue"),
+	),
+	StepMode: key.NewBinding(
+		key.WithKeys("m"),
+		key.WithHelp("m", "step mode"),
+	),
+	Note: key.NewBinding(
+		key.WithKeys("g"),
+		key.WithHelp("g", "guide agent"),
+	),
+	Stop: key.NewBinding(
+		key.WithKeys("x"),
+		key.WithHelp("x", "stop session"),
+	),
+	// Step-mode gate
+	Approve: key.NewBinding(
+		key.WithKeys("a"),
+		key.WithHelp("a", "approve"),
+	),
+	Retry: key.NewBinding(
+		key.WithKeys("r"),
+		key.WithHelp("r", "retry story"),
+	),
+	SkipStory: key.NewBinding(
+		key.WithKeys("x"),
+		key.WithHelp("x", "skip story"),
+	),
+	EditPRD: key.NewBinding(
+		key.WithKeys("e"),
+		key.WithHelp("e", "edit PRD"),
+	),
+	// Stories view editing
+	AddStory: key.NewBinding(
+		key.WithKeys("a"),
+		key.WithHelp("a", "add"),
+	),
+	EditJSON: key.NewBinding(
+		key.WithKeys("e"),
+		key.WithHelp("e/E", "edit json/yaml"),
+	),
+	EditYAML: key.NewBinding(
+		key.WithKeys("E"),
+		key.WithHelp("E", "edit yaml"),
+	),
+	DeleteStory: key.NewBinding(
+		key.WithKeys("d"),
+		key.WithHelp("d", "delete"),
+	),
+	TogglePasses: key.NewBinding(
+		key.WithKeys(" "),
+		key.WithHelp("Space", "passes"),
+	),
+	RaisePrio: key.NewBinding(
+		key.WithKeys("+", "="),
+		key.WithHelp("+/-", "priority"),
+	),
+	LowerPrio: key.NewBinding(
+		key.WithKeys("-"),
+		key.WithHelp("-", "lower priority"),
+	),
+	MoveUp: key.NewBinding(
+		key.WithKeys("K"),
+		key.WithHelp("K/J", "move"),
+	),
+	MoveDown: key.NewBinding(
+		key.WithKeys("J"),
+		key.WithHelp("J", "move down"),
+	),
 	Timestamps: key.NewBinding(
 		key.WithKeys("t"),
 		key.WithHelp("t", "timestamps"),
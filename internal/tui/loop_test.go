@@ -0,0 +1,104 @@
+package tui
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zhrkvl/ralph-go/internal/prd"
+	"github.com/zhrkvl/ralph-go/internal/session"
+	"github.com/zhrkvl/ralph-go/internal/tracker"
+)
+
+const loopPRD = `{
+  "project": "Demo",
+  "branchName": "ralph/demo",
+  "userStories": [
+    {"id": "US-001", "title": "One", "priority": 1, "passes": false, "acceptanceCriteria": ["works"]},
+    {"id": "US-002", "title": "Two", "priority": 2, "passes": false, "acceptanceCriteria": ["works"]}
+  ]
+}
+`
+
+const loopFixture = `{"default": [
+  {"output": "Working on {{story}}"},
+  {"write": "out/{{story}}.txt", "content": "done\n"},
+  {"pass": "{{story}}"},
+  {"complete": true}
+]}
+`
+
+// TestScriptedLoop runs the loop headless with the scripted agent until
+// every story passes.
+func TestScriptedLoop(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{"prd.json": loopPRD, "fixture.json": loopFixture} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	trk, err := tracker.New("json", tracker.Options{RalphDir: dir, ProjectDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := trk.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess := session.NewSession(dir, trk.Path(), "scripted", trk.Name(), 5, p)
+
+	var log bytes.Buffer
+	err = Run(Options{
+		PRD:           p,
+		Tracker:       trk,
+		RalphDir:      dir,
+		ProjectDir:    dir,
+		AgentName:     "scripted",
+		MaxIterations: 5,
+		Session:       sess,
+		Headless:      true,
+		Log:           &log,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(log.String(), "All tasks completed!") {
+		t.Errorf("loop did not complete; output:\n%s", log.String())
+	}
+	after, err := prd.Load(filepath.Join(dir, "prd.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := after.RemainingCount(); n != 0 {
+		t.Errorf("%d stories still open", n)
+	}
+	for _, id := range []string{"US-001", "US-002"} {
+		if _, err := os.Stat(filepath.Join(dir, "out", id+".txt")); err != nil {
+			t.Errorf("fixture file for %s: %v", id, err)
+		}
+	}
+
+	saved, err := session.Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Status != "completed" || saved.CurrentIteration != 2 {
+		t.Errorf("saved session: status %q, iteration %d; want completed, 2", saved.Status, saved.CurrentIteration)
+	}
+	logs, _ := filepath.Glob(filepath.Join(dir, ".ralph-tui", "iterations", "*_US-00?.log"))
+	if len(logs) != 2 {
+		t.Fatalf("iteration logs: %v; want one per story", logs)
+	}
+	for _, l := range logs {
+		data, _ := os.ReadFile(l)
+		if !strings.Contains(string(data), "Working on US-00") {
+			t.Errorf("%s does not hold the agent output:\n%s", filepath.Base(l), data)
+		}
+		if _, err := os.Stat(filepath.Join(filepath.Dir(l), session.RawStreamPath(filepath.Base(l)))); err != nil {
+			t.Errorf("raw stream of %s: %v", filepath.Base(l), err)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/zhrkvl/ralph-go/internal/agent"
+	"github.com/zhrkvl/ralph-go/internal/git"
+	"github.com/zhrkvl/ralph-go/internal/tracker"
+)
+
+// handleSignals reacts to control signals in a line of agent output.
+// Completion and story-done are recorded for the end of the iteration (a
+// finished story is also marked as passing in the tracker); blocked holds
+// the loop after this iteration; abort stops it right away.
+func (m *Model) handleSignals(line agent.Line) tea.Cmd {
+	var cmds []tea.Cmd
+	for _, sig := range m.signals.Match(line) {
+		switch sig.Kind {
+		case agent.SignalComplete:
+			m.iterCompleted = true
+
+		case agent.SignalStoryDone:
+			if slices.Contains(m.iterStoriesDone, sig.StoryID) {
+				continue
+			}
+			m.iterStoriesDone = append(m.iterStoriesDone, sig.StoryID)
+			m.appendOutput(accentStyle.Render("✓ Story " + sig.StoryID + " done"))
+			if m.sess != nil {
+				m.sess.MarkTaskCompleted(sig.StoryID)
+			}
+			if m.tracker != nil && !m.storyPasses(sig.StoryID) {
+				cmds = append(cmds, m.markDoneCmd(sig.StoryID))
+			}
+
+		case agent.SignalBlocked:
+			if m.blocked {
+				continue
+			}
+			m.blocked = true
+			m.blockedReason = sig.Reason
+			msg := "⚠ Agent is blocked and needs a human"
+			if sig.Reason != "" {
+				msg += ": " + sig.Reason
+			}
+			m.appendOutput(warnStyle.Render(msg))
+
+		case agent.SignalAbort:
+			if m.iterAborted {
+				continue
+			}
+			m.iterAborted = true
+			msg := "✗ Agent aborted the session"
+			if sig.Reason != "" {
+				msg += ": " + sig.Reason
+			}
+			m.appendOutput(errorStyle.Render(msg))
+			m.killAgent()
+		}
+	}
+	return tea.Batch(cmds...)
+}
+
+// storyMarkedMsg reports the tracker update after a story-done signal.
+type storyMarkedMsg struct {
+	id  string
+	err error
+}
+
+// markDoneCmd tells the tracker a story passes, for agents that signal
+// a finished story without updating the story list themselves. Trackers
+// that keep a record (issue comments) get a summary of the iteration.
+func (m *Model) markDoneCmd(id string) tea.Cmd {
+	t := m.tracker
+	projectDir, baseRev := m.projectDir, m.iterBaseRev
+	summary := fmt.Sprintf("Completed by ralph in iteration %d", m.iteration)
+	if m.sess != nil {
+		summary += fmt.Sprintf(" of session %s", m.sess.SessionID)
+	}
+	summary += "."
+	return func() tea.Msg {
+		c, ok := t.(tracker.Completer)
+		if !ok {
+			return storyMarkedMsg{id: id, err: t.MarkDone(id)}
+		}
+		if stat, err := git.DiffStat(projectDir, baseRev); err == nil && stat != "" {
+			summary += "\n\n```\n" + stat + "\n```"
+		}
+		return storyMarkedMsg{id: id, err: c.Complete(id, summary)}
+	}
+}
+
+// storyPasses reports whether the story is already marked as passing.
+func (m *Model) storyPasses(id string) bool {
+	if m.prd == nil {
+		return false
+	}
+	s, err := m.prd.Story(id)
+	return err == nil && s.Passes
+}
+
+// resetSignals clears per-iteration signal state before a new iteration.
+func (m *Model) resetSignals() {
+	m.iterCompleted = false
+	m.iterAborted = false
+	m.iterKilled = false
+	m.iterStoriesDone = nil
+}
+
+// continueLoop releases a loop held by a blocked signal, or starts it
+// after review.
+func (m *Model) continueLoop() tea.Cmd {
+	if m.reviewing {
+		m.reviewing = false
+		m.activeView = viewDashboard
+		m.appendOutput(accentStyle.Render("▶ Starting the loop"))
+		return m.startAgentCmd()
+	}
+	if !m.blocked || m.agentRunning {
+		return nil
+	}
+	m.blocked = false
+	m.blockedReason = ""
+	m.sessionStatus = "running"
+	m.appendOutput(accentStyle.Render(fmt.Sprintf("▶ Continuing with iteration %d", m.iteration+1)))
+	return m.startAgentCmd()
+}
@@ -1,19 +1,44 @@
 package tui
 
-import "fmt"
+import (
+	"fmt"
 
-func renderStatusBar(view View, agentRunning bool, width int) string {
+	"github.com/charmbracelet/bubbles/key"
+)
+
+func renderStatusBar(m *Model) string {
 	var hints []string
+	view := m.activeView
+	width := m.width
 
 	hints = append(hints, keyHint("Tab", viewName(nextView(view))))
 
-	if agentRunning {
+	if m.agentRunning {
 		hints = append(hints, keyHint("p", "pause"))
 		hints = append(hints, keyHint("s", "skip"))
+	} else if m.reviewing {
+		hints = append(hints, keyHint("c", "start loop"))
+	} else if m.blocked {
+		hints = append(hints, keyHint("c", "continue"))
+	}
+
+	stepDesc := "step mode"
+	if m.stepMode {
+		stepDesc = "step mode off"
 	}
+	hints = append(hints, keyHint("m", stepDesc))
+	hints = append(hints, keyHint("g", "guide"))
 
 	switch view {
-	case viewStories, viewHistory:
+	case viewStories:
+		hints = append(hints, keyHint("Enter", "select"))
+		hints = append(hints, bindingHint(keys.AddStory))
+		hints = append(hints, bindingHint(keys.EditJSON))
+		hints = append(hints, bindingHint(keys.DeleteStory))
+		hints = append(hints, bindingHint(keys.TogglePasses))
+		hints = append(hints, bindingHint(keys.RaisePrio))
+		hints = append(hints, bindingHint(keys.MoveUp))
+	case viewHistory:
 		hints = append(hints, keyHint("Enter", "select"))
 	case viewStoryDetail, viewHistoryDetail:
 		hints = append(hints, keyHint("Esc", "back"))
@@ -42,6 +67,11 @@ func keyHint(k, desc string) string {
 	return fmt.Sprintf("%s:%s", keyHintKeyStyle.Render(k), keyHintDescStyle.Render(desc))
 }
 
+// bindingHint is the keyHint of a binding, from its help.
+func bindingHint(b key.Binding) string {
+	return keyHint(b.Help().Key, b.Help().Desc)
+}
+
 func viewName(v View) string {
 	switch v {
 	case viewDashboard:
@@ -18,13 +18,19 @@ func renderStories(m *Model) string {
 		titleStyle.Render("Stories"),
 		m.prd.TotalCount(),
 	)
+	if m.storiesMsg != "" {
+		header += "  " + dimStyle.Render(m.storiesMsg)
+	}
+	if m.prdWarning != "" {
+		header += "  " + warnStyle.Render("⚠ "+m.prdWarning)
+	}
 	b.WriteString(header)
 	b.WriteString("\n")
 	b.WriteString(separator(w))
 	b.WriteString("\n")
 
 	stories := m.prd.UserStories
-	visibleHeight := m.height - 5 // header, separator, status bar, padding
+	visibleHeight := m.height - 6 // header, separator, prompt line, status bar, padding
 
 	// Calculate scroll window
 	startIdx := m.storyCursor - visibleHeight/2
@@ -47,7 +53,10 @@ func renderStories(m *Model) string {
 			icon = storyCompletedStyle.Render("✓")
 		}
 
-		line := fmt.Sprintf(" %s %-7s %s", icon, s.ID, s.Title)
+		line := fmt.Sprintf(" %s %-7s %s %s", icon, s.ID, dimStyle.Render(fmt.Sprintf("P%-2d", s.Priority)), s.Title)
+		if c, ok := m.storyChanges[s.ID]; ok {
+			line += "  " + renderChange(c, m.recentlyChanged(s.ID))
+		}
 
 		if i == m.storyCursor {
 			// Highlight selected line
@@ -69,6 +78,16 @@ func renderStories(m *Model) string {
 		b.WriteString("\n")
 	}
 
+	// Edit prompts
+	switch {
+	case m.addingStory:
+		b.WriteString(m.storyInput.View())
+		b.WriteString("\n")
+	case m.confirmDeleteID != "":
+		b.WriteString(warnStyle.Render(fmt.Sprintf("Delete %s? (y/n)", m.confirmDeleteID)))
+		b.WriteString("\n")
+	}
+
 	return b.String()
 }
 
@@ -0,0 +1,287 @@
+package tui
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/zhrkvl/ralph-go/internal/prd"
+	"github.com/zhrkvl/ralph-go/internal/tracker"
+	"gopkg.in/yaml.v3"
+)
+
+// storyEditedMsg is sent when $EDITOR exits after editing a single story.
+type storyEditedMsg struct {
+	id     string
+	path   string
+	format string // json or yaml
+	err    error
+}
+
+func newStoryInput() textinput.Model {
+	ti := textinput.New()
+	ti.Prompt = "New story title: "
+	ti.CharLimit = 200
+	return ti
+}
+
+// selectedStoryID returns the ID of the story under the cursor.
+func (m *Model) selectedStoryID() string {
+	if m.prd == nil || m.storyCursor >= len(m.prd.UserStories) {
+		return ""
+	}
+	return m.prd.UserStories[m.storyCursor].ID
+}
+
+// handleStoryEditKey handles the PRD editing keys in the Stories view.
+// Returns false if the key is not an editing action.
+func (m *Model) handleStoryEditKey(msg tea.KeyMsg) (bool, tea.Cmd) {
+	if key.Matches(msg, keys.AddStory) {
+		m.addingStory = true
+		m.storyInput.Reset()
+		m.storyInput.Width = m.width - len(m.storyInput.Prompt) - 2
+		return true, m.storyInput.Focus()
+	}
+	if !key.Matches(msg, keys.EditJSON, keys.EditYAML, keys.DeleteStory, keys.TogglePasses,
+		keys.RaisePrio, keys.LowerPrio, keys.MoveUp, keys.MoveDown) {
+		return false, nil
+	}
+	id := m.selectedStoryID()
+	if id == "" {
+		return true, nil
+	}
+	switch {
+	case key.Matches(msg, keys.EditJSON):
+		return true, m.editStoryCmd(id, "json")
+	case key.Matches(msg, keys.EditYAML):
+		return true, m.editStoryCmd(id, "yaml")
+	case key.Matches(msg, keys.DeleteStory):
+		m.confirmDeleteID = id
+	case key.Matches(msg, keys.TogglePasses):
+		return true, m.updatePRD(fmt.Sprintf("Toggled passes on %s", id), id, func(p *prd.PRD) error {
+			s, err := p.Story(id)
+			if err != nil {
+				return err
+			}
+			s.Passes = !s.Passes
+			return nil
+		})
+	case key.Matches(msg, keys.RaisePrio):
+		return true, m.updatePRD(fmt.Sprintf("Raised priority of %s", id), id, func(p *prd.PRD) error {
+			s, err := p.Story(id)
+			if err != nil {
+				return err
+			}
+			if s.Priority > 1 {
+				s.Priority--
+			}
+			return nil
+		})
+	case key.Matches(msg, keys.LowerPrio):
+		return true, m.updatePRD(fmt.Sprintf("Lowered priority of %s", id), id, func(p *prd.PRD) error {
+			s, err := p.Story(id)
+			if err != nil {
+				return err
+			}
+			s.Priority++
+			return nil
+		})
+	case key.Matches(msg, keys.MoveUp, keys.MoveDown):
+		delta := 1
+		if key.Matches(msg, keys.MoveUp) {
+			delta = -1
+		}
+		return true, m.updatePRD(fmt.Sprintf("Moved %s", id), id, func(p *prd.PRD) error {
+			return p.Move(id, delta)
+		})
+	}
+	return true, nil
+}
+
+// handleAddStoryKey routes keys to the new-story input while it is open.
+func (m *Model) handleAddStoryKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.addingStory = false
+		m.storyInput.Blur()
+		return m, nil
+	case tea.KeyEnter:
+		title := strings.TrimSpace(m.storyInput.Value())
+		m.addingStory = false
+		m.storyInput.Blur()
+		if title == "" {
+			return m, nil
+		}
+		return m, m.addStoryCmd(title)
+	}
+	var cmd tea.Cmd
+	m.storyInput, cmd = m.storyInput.Update(msg)
+	return m, cmd
+}
+
+// handleDeleteConfirmKey answers the delete confirmation prompt.
+func (m *Model) handleDeleteConfirmKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	id := m.confirmDeleteID
+	m.confirmDeleteID = ""
+	if msg.String() == "y" || msg.String() == "Y" {
+		return m, m.updatePRD("Deleted "+id, "", func(p *prd.PRD) error {
+			return p.Delete(id)
+		})
+	}
+	return m, nil
+}
+
+// storiesUpdatedMsg reports an edit made from the Stories view.
+type storiesUpdatedMsg struct {
+	p      *prd.PRD
+	err    error
+	done   string // shown once the edit is in
+	follow string // story the cursor moves to, if any
+}
+
+// updatePRD applies fn to the latest stories through the tracker in the
+// background. applyStoriesUpdate reports the outcome and moves the cursor
+// to follow.
+func (m *Model) updatePRD(done, follow string, fn func(*prd.PRD) error) tea.Cmd {
+	e, err := m.storyEditor()
+	if err != nil {
+		m.storiesMsg = "Save failed: " + err.Error()
+		return nil
+	}
+	return func() tea.Msg {
+		p, err := e.Update(fn)
+		return storiesUpdatedMsg{p: p, err: err, done: done, follow: follow}
+	}
+}
+
+// addStoryCmd adds a story with the next free ID, which is only known once
+// the tracker has the latest stories.
+func (m *Model) addStoryCmd(title string) tea.Cmd {
+	e, err := m.storyEditor()
+	if err != nil {
+		m.storiesMsg = "Save failed: " + err.Error()
+		return nil
+	}
+	return func() tea.Msg {
+		var added string
+		p, err := e.Update(func(p *prd.PRD) error {
+			added = p.Add(title).ID
+			return nil
+		})
+		return storiesUpdatedMsg{p: p, err: err, done: "Added " + added, follow: added}
+	}
+}
+
+// applyStoriesUpdate takes in the result of updatePRD or addStoryCmd.
+func (m *Model) applyStoriesUpdate(msg storiesUpdatedMsg) {
+	if msg.err != nil {
+		m.storiesMsg = "Save failed: " + msg.err.Error()
+		return
+	}
+	m.setPRD(msg.p)
+	m.storiesMsg = msg.done
+	if i := m.prd.Index(msg.follow); msg.follow != "" && i >= 0 {
+		m.storyCursor = i
+	}
+	clampStoryCursor(m)
+}
+
+// setPRD installs stories the tracker returned after an edit.
+func (m *Model) setPRD(p *prd.PRD) {
+	m.prd = p
+	if m.sess != nil {
+		m.sess.TasksCompleted = p.CompletedCount()
+	}
+}
+
+// storyEditor returns the tracker as an Editor, if it supports editing.
+func (m *Model) storyEditor() (tracker.Editor, error) {
+	if m.tracker == nil {
+		return nil, fmt.Errorf("no PRD to edit")
+	}
+	e, ok := m.tracker.(tracker.Editor)
+	if !ok {
+		return nil, fmt.Errorf("stories from the %s tracker cannot be edited here", m.tracker.Name())
+	}
+	return e, nil
+}
+
+// editStoryCmd writes a single story to a temp file and opens it in the
+// editor. The result is applied in applyStoryEdit.
+func (m *Model) editStoryCmd(id, format string) tea.Cmd {
+	s, err := m.prd.Story(id)
+	if err != nil {
+		m.storiesMsg = err.Error()
+		return nil
+	}
+
+	var data []byte
+	if format == "yaml" {
+		data, err = yaml.Marshal(s)
+	} else {
+		data, err = json.MarshalIndent(s, "", "  ")
+		data = append(data, '\n')
+	}
+	if err != nil {
+		m.storiesMsg = "Encoding story failed: " + err.Error()
+		return nil
+	}
+
+	f, err := os.CreateTemp("", "ralph-story-*."+format)
+	if err != nil {
+		m.storiesMsg = "Creating temp file failed: " + err.Error()
+		return nil
+	}
+	f.Write(data)
+	f.Close()
+
+	c := editorCommand(f.Name())
+	return tea.ExecProcess(c, func(err error) tea.Msg {
+		return storyEditedMsg{id: id, path: f.Name(), format: format, err: err}
+	})
+}
+
+// applyStoryEdit parses the edited story and writes it back to the PRD.
+func (m *Model) applyStoryEdit(msg storyEditedMsg) tea.Cmd {
+	defer os.Remove(msg.path)
+	if msg.err != nil {
+		m.storiesMsg = "Editor failed: " + msg.err.Error()
+		return nil
+	}
+	data, err := os.ReadFile(msg.path)
+	if err != nil {
+		m.storiesMsg = "Reading edited story failed: " + err.Error()
+		return nil
+	}
+	var s prd.UserStory
+	if msg.format == "yaml" {
+		err = yaml.Unmarshal(data, &s)
+	} else {
+		err = json.Unmarshal(data, &s)
+	}
+	if err != nil {
+		m.storiesMsg = "Edited story is invalid, not saved: " + err.Error()
+		return nil
+	}
+	return m.updatePRD("Saved "+s.ID, s.ID, func(p *prd.PRD) error {
+		return p.Replace(msg.id, s)
+	})
+}
+
+// editorCommand builds the command that opens path in $VISUAL/$EDITOR.
+func editorCommand(path string) *exec.Cmd {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	args := append(strings.Fields(editor), path)
+	return exec.Command(args[0], args[1:]...)
+}
@@ -0,0 +1,72 @@
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/zhrkvl/ralph-go/internal/prd"
+	"github.com/zhrkvl/ralph-go/internal/tracker"
+)
+
+// countingEditor counts the tracker updates made through it.
+type countingEditor struct {
+	tracker.Tracker
+	updates int
+}
+
+func (c *countingEditor) Update(fn func(*prd.PRD) error) (*prd.PRD, error) {
+	c.updates++
+	return c.Tracker.(tracker.Editor).Update(fn)
+}
+
+// TestStoryEditsRunInCmds checks that editing keys leave the tracker
+// alone until their command runs, and that the result comes back as a
+// message.
+func TestStoryEditsRunInCmds(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "prd.json"), []byte(loopPRD), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	trk, err := tracker.New("json", tracker.Options{RalphDir: dir, ProjectDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := trk.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ed := &countingEditor{Tracker: trk}
+	var m tea.Model = NewModel(Options{PRD: p, Tracker: ed, RalphDir: dir, ProjectDir: dir, Review: true})
+
+	m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("J")})
+	if ed.updates != 0 || cmd == nil {
+		t.Fatalf("J: %d updates in Update, cmd %v; want none and a cmd", ed.updates, cmd)
+	}
+	msg := cmd()
+	if ed.updates != 1 {
+		t.Fatalf("cmd made %d updates, want 1", ed.updates)
+	}
+	m, _ = m.Update(msg)
+	got := asModel(m)
+	if got.storiesMsg != "Moved US-001" || got.prd.UserStories[1].ID != "US-001" || got.storyCursor != 1 {
+		t.Errorf("after move: msg %q, order %s %s, cursor %d", got.storiesMsg,
+			got.prd.UserStories[0].ID, got.prd.UserStories[1].ID, got.storyCursor)
+	}
+
+	// Nothing selected: the key is swallowed without a save.
+	got.prd = &prd.PRD{}
+	m, cmd = got.Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(" ")})
+	if cmd != nil || asModel(m).storiesMsg != "Moved US-001" {
+		t.Errorf("space on an empty list: cmd %v, msg %q", cmd, asModel(m).storiesMsg)
+	}
+}
+
+// asModel unwraps the model Update returned; key handlers return a pointer.
+func asModel(m tea.Model) Model {
+	if p, ok := m.(*Model); ok {
+		return *p
+	}
+	return m.(Model)
+}
@@ -0,0 +1,126 @@
+package tui
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/zhrkvl/ralph-go/internal/prd"
+	"github.com/zhrkvl/ralph-go/internal/watch"
+)
+
+// fileDebounce coalesces the write bursts of agents and editors.
+const fileDebounce = 150 * time.Millisecond
+
+// recentChange is how long a changed story stays highlighted.
+const recentChange = 30 * time.Second
+
+type fileChangedMsg struct{ path string }
+
+// storyChange records the last externally observed change to a story.
+type storyChange struct {
+	what string // passed, reopened, added, edited
+	at   time.Time
+}
+
+// watchedFiles lists the files ralph reacts to: the PRD, the progress log
+// and the agent prompt files.
+func watchedFiles(prdPath, ralphDir string) []string {
+	var paths []string
+	if prdPath != "" {
+		paths = append(paths, prdPath)
+	}
+	if ralphDir != "" {
+		paths = append(paths,
+			filepath.Join(ralphDir, "progress.txt"),
+			filepath.Join(ralphDir, "CLAUDE.md"),
+			filepath.Join(ralphDir, "prompt.md"),
+		)
+	}
+	return paths
+}
+
+func waitForFileChange(w *watch.Watcher) tea.Cmd {
+	if w == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		path, ok := <-w.Events()
+		if !ok {
+			return nil
+		}
+		return fileChangedMsg{path: path}
+	}
+}
+
+// handleFileChanged reacts to a watched file changing on disk.
+func (m *Model) handleFileChanged(msg fileChangedMsg) tea.Cmd {
+	cmds := []tea.Cmd{waitForFileChange(m.watcher)}
+	switch filepath.Base(msg.path) {
+	case "progress.txt":
+		m.progressUpdatedAt = time.Now()
+	case "CLAUDE.md", "prompt.md":
+		m.appendOutput(dimStyle.Render(fmt.Sprintf("%s changed, used from the next iteration", filepath.Base(msg.path))))
+	default:
+		cmds = append(cmds, reloadPRD(m.tracker))
+	}
+	return tea.Batch(cmds...)
+}
+
+// noteStoryChanges compares a freshly loaded PRD against the one on screen
+// and remembers which stories changed. It returns the stories that now
+// pass.
+func (m *Model) noteStoryChanges(old, cur *prd.PRD) (passed []prd.UserStory) {
+	if old == nil || cur == nil {
+		return nil
+	}
+	if m.storyChanges == nil {
+		m.storyChanges = make(map[string]storyChange)
+	}
+	now := time.Now()
+	before := make(map[string]prd.UserStory, len(old.UserStories))
+	for _, s := range old.UserStories {
+		before[s.ID] = s
+	}
+	for _, s := range cur.UserStories {
+		prev, existed := before[s.ID]
+		var what string
+		switch {
+		case !existed:
+			what = "added"
+		case s.Passes && !prev.Passes:
+			what = "passed"
+		case !s.Passes && prev.Passes:
+			what = "reopened"
+		case !reflect.DeepEqual(s, prev):
+			what = "edited"
+		default:
+			continue
+		}
+		m.storyChanges[s.ID] = storyChange{what: what, at: now}
+		m.lastChangeID = s.ID
+		if what == "passed" {
+			m.appendOutput(storyCompletedStyle.Render(fmt.Sprintf("✓ %s passed", s.ID)))
+			passed = append(passed, s)
+		}
+	}
+	return passed
+}
+
+// recentlyChanged reports whether the story changed within recentChange.
+func (m *Model) recentlyChanged(id string) bool {
+	c, ok := m.storyChanges[id]
+	return ok && time.Since(c.at) < recentChange
+}
+
+// renderChange formats a story change for the list views, e.g.
+// "passed 14:03:05".
+func renderChange(c storyChange, recent bool) string {
+	s := fmt.Sprintf("%s %s", c.what, c.at.Format("15:04:05"))
+	if recent {
+		return warnStyle.Render(s)
+	}
+	return dimStyle.Render(s)
+}
@@ -0,0 +1,128 @@
+// Package watch reports changes to a fixed set of files, coalescing the
+// bursts of events editors and atomic writers produce into one notification
+// per file.
+package watch
+
+import (
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watcher watches individual files. It watches their parent directories
+// rather than the files themselves, so files that are replaced by rename
+// (atomic saves, most editors) or do not exist yet are still tracked.
+type Watcher struct {
+	fs       *fsnotify.Watcher
+	files    map[string]bool
+	debounce time.Duration
+	events   chan string
+
+	mu      sync.Mutex
+	pending map[string]*time.Timer
+	closed  bool
+	stop    chan struct{} // closed by Close, unblocks pending sends
+	done    chan struct{} // closed when loop exits
+}
+
+// New starts watching paths. A change is reported on Events once no further
+// event for the same file arrived for debounce.
+func New(paths []string, debounce time.Duration) (*Watcher, error) {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher{
+		fs:       fw,
+		files:    make(map[string]bool),
+		debounce: debounce,
+		events:   make(chan string, 16),
+		pending:  make(map[string]*time.Timer),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	dirs := make(map[string]bool)
+	for _, p := range paths {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			fw.Close()
+			return nil, err
+		}
+		w.files[abs] = true
+		dirs[filepath.Dir(abs)] = true
+	}
+	for dir := range dirs {
+		if err := fw.Add(dir); err != nil {
+			fw.Close()
+			return nil, err
+		}
+	}
+	go w.loop()
+	return w, nil
+}
+
+// Events delivers the path of each changed file, as passed to New but made
+// absolute.
+func (w *Watcher) Events() <-chan string { return w.events }
+
+// Close stops watching. Pending notifications are dropped.
+func (w *Watcher) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	close(w.stop)
+	for _, t := range w.pending {
+		t.Stop()
+	}
+	w.mu.Unlock()
+	err := w.fs.Close()
+	<-w.done
+	return err
+}
+
+func (w *Watcher) loop() {
+	defer close(w.done)
+	for {
+		select {
+		case ev, ok := <-w.fs.Events:
+			if !ok {
+				return
+			}
+			if ev.Op == fsnotify.Chmod || !w.files[filepath.Clean(ev.Name)] {
+				continue
+			}
+			w.schedule(filepath.Clean(ev.Name))
+		case _, ok := <-w.fs.Errors:
+			if !ok {
+				return
+			}
+		}
+	}
+}
+
+// schedule (re)starts the debounce timer for path.
+func (w *Watcher) schedule(path string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return
+	}
+	if t, ok := w.pending[path]; ok {
+		t.Reset(w.debounce)
+		return
+	}
+	w.pending[path] = time.AfterFunc(w.debounce, func() {
+		w.mu.Lock()
+		delete(w.pending, path)
+		w.mu.Unlock()
+		select {
+		case w.events <- path:
+		case <-w.stop:
+		}
+	})
+}
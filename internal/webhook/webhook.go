@@ -0,0 +1,309 @@
+// Package webhook posts loop events as JSON to HTTP endpoints, such as a
+// Slack or Mattermost incoming webhook or an ntfy topic.
+//
+// Deliveries are queued and sent by one goroutine per endpoint, so a slow
+// or unreachable endpoint never holds up the loop, and each endpoint sees
+// events in order. Failed deliveries are retried with exponential backoff.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zhrkvl/ralph-go/internal/config"
+	"github.com/zhrkvl/ralph-go/internal/hooks"
+)
+
+// Body is what an endpoint receives: the hook payload plus a one-line
+// summary in text, which Slack and Mattermost display as the message.
+type Body struct {
+	hooks.Payload
+	Text string `json:"text"`
+}
+
+// Headers sent with every delivery. The signature is
+// "sha256=" + hex(HMAC-SHA256(secret, body)) and is only sent when the
+// endpoint has a secret. The delivery ID stays the same across retries.
+const (
+	HeaderEvent     = "X-Ralph-Event"
+	HeaderDelivery  = "X-Ralph-Delivery"
+	HeaderSignature = "X-Ralph-Signature"
+)
+
+const (
+	queueSize      = 64
+	defaultRetries = 3
+	defaultTimeout = 10 * time.Second
+	maxBackoff     = 30 * time.Second
+)
+
+// firstBackoff is the wait before the first retry, doubled for each
+// further one. Tests shorten it.
+var firstBackoff = time.Second
+
+// Dispatcher sends events to the configured endpoints. A nil Dispatcher
+// sends nothing.
+type Dispatcher struct {
+	endpoints []*endpoint
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+
+	mu      sync.Mutex
+	closed  bool
+	onError func(error)
+}
+
+type endpoint struct {
+	url     string
+	secret  string
+	events  []hooks.Event // nil: all events
+	retries int
+	client  *http.Client
+	queue   chan delivery
+}
+
+type delivery struct {
+	id    string
+	event hooks.Event
+	body  []byte
+}
+
+// New starts a Dispatcher for the [[webhooks]] config, or returns nil if
+// there are no endpoints.
+func New(cfgs []config.Webhook) (*Dispatcher, error) {
+	if len(cfgs) == 0 {
+		return nil, nil
+	}
+	d := &Dispatcher{}
+	for i, c := range cfgs {
+		e, err := newEndpoint(c)
+		if err != nil {
+			return nil, fmt.Errorf("webhooks[%d]: %w", i, err)
+		}
+		d.endpoints = append(d.endpoints, e)
+	}
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+	for _, e := range d.endpoints {
+		d.wg.Add(1)
+		go d.work(e)
+	}
+	return d, nil
+}
+
+func newEndpoint(c config.Webhook) (*endpoint, error) {
+	u, err := url.Parse(c.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("url must be an http or https URL, not %q", c.URL)
+	}
+	e := &endpoint{
+		url:     c.URL,
+		secret:  c.Secret,
+		retries: c.Retries,
+		client:  &http.Client{Timeout: time.Duration(c.Timeout) * time.Second},
+		queue:   make(chan delivery, queueSize),
+	}
+	if e.secret == "" && c.SecretEnv != "" {
+		e.secret = os.Getenv(c.SecretEnv)
+	}
+	if e.retries <= 0 {
+		e.retries = defaultRetries
+	}
+	if e.client.Timeout <= 0 {
+		e.client.Timeout = defaultTimeout
+	}
+	for _, name := range c.Events {
+		ev := hooks.Event(name)
+		if !slices.Contains(hooks.Events, ev) {
+			names := make([]string, len(hooks.Events))
+			for i, known := range hooks.Events {
+				names[i] = string(known)
+			}
+			return nil, fmt.Errorf("unknown event %q. Must be one of: %s", name, strings.Join(names, ", "))
+		}
+		e.events = append(e.events, ev)
+	}
+	return e, nil
+}
+
+// OnError sets where failed deliveries are reported, after their last
+// retry. It is called from the sending goroutines.
+func (d *Dispatcher) OnError(f func(error)) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	d.onError = f
+	d.mu.Unlock()
+}
+
+// Send queues p for every endpoint that wants its event and returns at
+// once. If an endpoint's queue is full the event is dropped for it.
+func (d *Dispatcher) Send(p hooks.Payload) {
+	if d == nil {
+		return
+	}
+	if p.Time.IsZero() {
+		p.Time = time.Now().UTC()
+	}
+	body, err := json.Marshal(Body{Payload: p, Text: p.Title() + ": " + p.Summary()})
+	if err != nil {
+		d.report(err)
+		return
+	}
+	dl := delivery{id: uuid.NewString(), event: p.Event, body: body}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return
+	}
+	for _, e := range d.endpoints {
+		if e.events != nil && !slices.Contains(e.events, p.Event) {
+			continue
+		}
+		select {
+		case e.queue <- dl:
+		default:
+			go d.report(fmt.Errorf("webhook %s: queue full, dropped %s event", e.url, p.Event))
+		}
+	}
+}
+
+// Close stops accepting events and waits up to timeout for the queued
+// ones to be delivered. Deliveries still pending after that are dropped.
+func (d *Dispatcher) Close(timeout time.Duration) error {
+	if d == nil {
+		return nil
+	}
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		for _, e := range d.endpoints {
+			close(e.queue)
+		}
+	}
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+	defer d.cancel()
+	select {
+	case <-done:
+		return nil
+	case <-time.After(timeout):
+		return fmt.Errorf("webhooks: gave up on pending deliveries after %s", timeout)
+	}
+}
+
+func (d *Dispatcher) work(e *endpoint) {
+	defer d.wg.Done()
+	for dl := range e.queue {
+		if err := d.deliver(e, dl); err != nil {
+			d.report(err)
+		}
+	}
+}
+
+// deliver posts dl, retrying network errors, 429s and 5xx responses.
+func (d *Dispatcher) deliver(e *endpoint, dl delivery) error {
+	wait := firstBackoff
+	var err error
+	for attempt := 0; ; attempt++ {
+		var retry time.Duration
+		retry, err = e.post(d.ctx, dl)
+		if err == nil {
+			return nil
+		}
+		if retry < 0 || attempt == e.retries {
+			break
+		}
+		if retry == 0 {
+			retry = wait
+		}
+		select {
+		case <-time.After(min(retry, maxBackoff)):
+		case <-d.ctx.Done():
+			return fmt.Errorf("webhook %s: %s event: %w", e.url, dl.event, err)
+		}
+		wait *= 2
+	}
+	return fmt.Errorf("webhook %s: %s event: %w", e.url, dl.event, err)
+}
+
+// post sends one attempt. On failure it returns how long to wait before
+// retrying: 0 for the default backoff, or -1 if retrying is pointless.
+func (e *endpoint) post(ctx context.Context, dl delivery) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(dl.body))
+	if err != nil {
+		return -1, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ralph")
+	req.Header.Set(HeaderEvent, string(dl.event))
+	req.Header.Set(HeaderDelivery, dl.id)
+	if e.secret != "" {
+		req.Header.Set(HeaderSignature, Sign(e.secret, dl.body))
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode/100 == 2 {
+		return 0, nil
+	}
+	err = errors.New(resp.Status)
+	if msg := strings.TrimSpace(string(data)); msg != "" {
+		err = fmt.Errorf("%s: %s", resp.Status, msg)
+	}
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		if s, convErr := strconv.Atoi(resp.Header.Get("Retry-After")); convErr == nil && s > 0 {
+			return time.Duration(s) * time.Second, err
+		}
+		return 0, err
+	case resp.StatusCode >= 500:
+		return 0, err
+	default:
+		return -1, err
+	}
+}
+
+func (d *Dispatcher) report(err error) {
+	d.mu.Lock()
+	f := d.onError
+	d.mu.Unlock()
+	if f != nil {
+		f(err)
+	}
+}
+
+// Sign returns the X-Ralph-Signature value for body. Receivers recompute
+// it with their copy of the secret and compare with hmac.Equal.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
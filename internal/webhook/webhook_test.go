@@ -0,0 +1,207 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zhrkvl/ralph-go/internal/config"
+	"github.com/zhrkvl/ralph-go/internal/hooks"
+)
+
+// attempt is one request an endpoint received.
+type attempt struct {
+	at        time.Time
+	event     string
+	delivery  string
+	signature string
+	body      []byte
+}
+
+// recorder is an endpoint answering each attempt with the next status
+// in statuses, then 204.
+type recorder struct {
+	mu       sync.Mutex
+	statuses []int
+	attempts []attempt
+}
+
+func (r *recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	r.attempts = append(r.attempts, attempt{
+		at:        time.Now(),
+		event:     req.Header.Get(HeaderEvent),
+		delivery:  req.Header.Get(HeaderDelivery),
+		signature: req.Header.Get(HeaderSignature),
+		body:      body,
+	})
+	status := http.StatusNoContent
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	r.mu.Unlock()
+	w.WriteHeader(status)
+}
+
+func (r *recorder) got() []attempt {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]attempt(nil), r.attempts...)
+}
+
+func newRecorder(t *testing.T, statuses ...int) (*recorder, string) {
+	t.Helper()
+	r := &recorder{statuses: statuses}
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return r, srv.URL
+}
+
+func dispatch(t *testing.T, cfgs []config.Webhook, payloads ...hooks.Payload) []error {
+	t.Helper()
+	d, err := New(cfgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	var errs []error
+	d.OnError(func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	})
+	for _, p := range payloads {
+		d.Send(p)
+	}
+	if err := d.Close(5 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	return errs
+}
+
+func TestSignatureAndBody(t *testing.T) {
+	r, url := newRecorder(t)
+	dispatch(t, []config.Webhook{{URL: url, Secret: "s3cret"}},
+		hooks.Payload{Event: hooks.StoryPassed, ProjectDir: "/src/myapp", StoryID: "US-002", StoryTitle: "Login page"})
+
+	got := r.got()
+	if len(got) != 1 {
+		t.Fatalf("got %d attempts, want 1", len(got))
+	}
+	a := got[0]
+	if a.event != "storyPassed" || a.delivery == "" {
+		t.Errorf("event = %q, delivery = %q", a.event, a.delivery)
+	}
+	if !hmac.Equal([]byte(a.signature), []byte(Sign("s3cret", a.body))) {
+		t.Errorf("signature %q does not match the body", a.signature)
+	}
+	// printf '{}' | openssl dgst -sha256 -hmac key
+	if sig := Sign("key", []byte("{}")); sig != "sha256=a777724d943eb48dc69bca8a4a6d57a04db3f9ec7e1de4e581e860265bdf3032" {
+		t.Errorf("Sign = %q", sig)
+	}
+
+	var b Body
+	if err := json.Unmarshal(a.body, &b); err != nil {
+		t.Fatal(err)
+	}
+	if b.StoryID != "US-002" || b.Time.IsZero() || b.Text != "ralph [myapp]: "+b.Payload.Summary() {
+		t.Errorf("body = %+v", b)
+	}
+}
+
+func TestEventFilter(t *testing.T) {
+	all, allURL := newRecorder(t)
+	some, someURL := newRecorder(t)
+	dispatch(t, []config.Webhook{{URL: allURL}, {URL: someURL, Events: []string{"complete", "blocked"}}},
+		hooks.Payload{Event: hooks.IterationStart},
+		hooks.Payload{Event: hooks.Blocked},
+		hooks.Payload{Event: hooks.Complete})
+
+	var events []string
+	for _, a := range some.got() {
+		events = append(events, a.event)
+	}
+	if len(events) != 2 || events[0] != "blocked" || events[1] != "complete" {
+		t.Errorf("filtered endpoint got %q, want [blocked complete]", events)
+	}
+	if n := len(all.got()); n != 3 {
+		t.Errorf("unfiltered endpoint got %d events, want 3", n)
+	}
+
+	if _, err := New([]config.Webhook{{URL: allURL, Events: []string{"storyDone"}}}); err == nil {
+		t.Error("unknown event accepted")
+	}
+}
+
+func TestRetry(t *testing.T) {
+	firstBackoff = 20 * time.Millisecond
+	defer func() { firstBackoff = time.Second }()
+
+	r, url := newRecorder(t, http.StatusBadGateway, http.StatusTooManyRequests, http.StatusServiceUnavailable)
+	if errs := dispatch(t, []config.Webhook{{URL: url}}, hooks.Payload{Event: hooks.Complete}); len(errs) != 0 {
+		t.Fatalf("errors: %v", errs)
+	}
+	got := r.got()
+	if len(got) != 4 {
+		t.Fatalf("got %d attempts, want 4", len(got))
+	}
+	for i, a := range got[1:] {
+		if a.delivery != got[0].delivery {
+			t.Errorf("attempt %d has delivery %q, want %q", i+2, a.delivery, got[0].delivery)
+		}
+		// 20ms, 40ms, 80ms
+		if wait, min := a.at.Sub(got[i].at), firstBackoff<<i; wait < min {
+			t.Errorf("attempt %d came after %s, want at least %s", i+2, wait, min)
+		}
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	firstBackoff = time.Millisecond
+	defer func() { firstBackoff = time.Second }()
+
+	// A client error is not retried.
+	r, url := newRecorder(t, http.StatusNotFound)
+	if errs := dispatch(t, []config.Webhook{{URL: url}}, hooks.Payload{Event: hooks.Complete}); len(errs) != 1 {
+		t.Errorf("errors: %v, want one", errs)
+	}
+	if n := len(r.got()); n != 1 {
+		t.Errorf("404 tried %d times, want 1", n)
+	}
+
+	// Server errors are, up to retries times.
+	r, url = newRecorder(t, 500, 500, 500, 500)
+	if errs := dispatch(t, []config.Webhook{{URL: url, Retries: 2}}, hooks.Payload{Event: hooks.Complete}); len(errs) != 1 {
+		t.Errorf("errors: %v, want one", errs)
+	}
+	if n := len(r.got()); n != 3 {
+		t.Errorf("500 tried %d times, want 3", n)
+	}
+}
+
+func TestDeliveryIDs(t *testing.T) {
+	a, aURL := newRecorder(t)
+	b, bURL := newRecorder(t)
+	dispatch(t, []config.Webhook{{URL: aURL}, {URL: bURL}},
+		hooks.Payload{Event: hooks.IterationStart}, hooks.Payload{Event: hooks.IterationEnd})
+
+	ga, gb := a.got(), b.got()
+	if len(ga) != 2 || len(gb) != 2 {
+		t.Fatalf("got %d and %d attempts, want 2 each", len(ga), len(gb))
+	}
+	// One ID per event, shared by the endpoints it goes to.
+	if ga[0].delivery == ga[1].delivery {
+		t.Error("two events share a delivery ID")
+	}
+	if ga[0].delivery != gb[0].delivery || ga[1].delivery != gb[1].delivery {
+		t.Error("endpoints got different delivery IDs for the same event")
+	}
+}
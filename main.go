@@ -1,18 +1,28 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/zhrkvl/ralph-go/internal/agent"
+	"github.com/zhrkvl/ralph-go/internal/api"
 	"github.com/zhrkvl/ralph-go/internal/config"
+	"github.com/zhrkvl/ralph-go/internal/hooks"
+	"github.com/zhrkvl/ralph-go/internal/notify"
 	"github.com/zhrkvl/ralph-go/internal/prd"
 	"github.com/zhrkvl/ralph-go/internal/session"
+	"github.com/zhrkvl/ralph-go/internal/tracker"
 	"github.com/zhrkvl/ralph-go/internal/tui"
+	"github.com/zhrkvl/ralph-go/internal/webhook"
 )
 
 var (
@@ -22,8 +32,17 @@ var (
 	ralphDirFlag       string
 	projectDirFlag     string
 	installClaudeFlag  bool
+	stepFlag           bool
+	profileFlag        string
+	listenFlag         string
+	detachFlag         bool
+	daemonFlag         bool
 )
 
+// webhookDrainTimeout is how long ralph waits on exit for queued webhook
+// deliveries, including their retries.
+const webhookDrainTimeout = 15 * time.Second
+
 func main() {
 	rootCmd := &cobra.Command{
 		Use:   "ralph",
@@ -32,19 +51,57 @@ func main() {
 		RunE:  run,
 	}
 
-	rootCmd.Flags().StringVar(&toolFlag, "tool", "", "agent tool to use: amp or claude (default from config or amp)")
-	rootCmd.Flags().StringVar(&modelFlag, "model", "", "model to use (passed as --model to the agent)")
-	rootCmd.Flags().IntVar(&maxIterFlag, "max-iterations", 0, "maximum iterations (default from config or 10)")
-	rootCmd.Flags().StringVar(&ralphDirFlag, "ralph-dir", "", "directory containing prd.json and CLAUDE.md")
-	rootCmd.Flags().StringVar(&projectDirFlag, "project-dir", "", "working directory for agent (default: CWD)")
-	rootCmd.Flags().BoolVar(&installClaudeFlag, "install-claude", false, "download scripts/ralph (CLAUDE.md, ralph.sh) from github.com/snarktank/ralph into CWD")
+	addRunFlags(rootCmd)
+
+	rootCmd.AddCommand(newRunCmd())
+	rootCmd.AddCommand(newReplayCmd())
+	rootCmd.AddCommand(newValidateCmd())
+	rootCmd.AddCommand(newPlanCmd())
+	rootCmd.AddCommand(newSyncCmd())
+	rootCmd.AddCommand(newConfigCmd())
+	rootCmd.AddCommand(newInitCmd())
+	rootCmd.AddCommand(newStatusCmd())
+	rootCmd.AddCommand(newAttachCmd())
+	rootCmd.AddCommand(newStopCmd())
 
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
 	}
 }
 
+func newRunCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "run",
+		Short: "Run the agent loop, as ralph without a command does",
+		Args:  cobra.NoArgs,
+		RunE:  run,
+	}
+	addRunFlags(cmd)
+	return cmd
+}
+
+// addRunFlags adds the flags of the agent loop to cmd.
+func addRunFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&toolFlag, "tool", "", "agent tool to use: amp, claude or scripted (default from config or amp)")
+	cmd.Flags().StringVar(&modelFlag, "model", "", "model to use (passed as --model to the agent)")
+	cmd.Flags().IntVar(&maxIterFlag, "max-iterations", 0, "maximum iterations (default from config or 10)")
+	cmd.Flags().StringVar(&ralphDirFlag, "ralph-dir", "", "directory containing the PRD (prd.json, prd.yaml or prd.md) and CLAUDE.md")
+	cmd.Flags().StringVar(&projectDirFlag, "project-dir", "", "working directory for agent (default: CWD)")
+	cmd.Flags().BoolVar(&stepFlag, "step", false, "pause after each iteration for approval (toggle in the TUI with m)")
+	cmd.Flags().StringVar(&profileFlag, "profile", "", "config profile to use, from [profiles.<name>] in config.toml")
+	cmd.Flags().StringVar(&listenFlag, "listen", "", "serve the HTTP API on a loopback host:port or unix:<path>")
+	cmd.Flags().BoolVar(&strictFlag, "strict", false, "refuse to start if the PRD has validation warnings")
+	cmd.Flags().BoolVar(&installClaudeFlag, "install-claude", false, "download scripts/ralph (CLAUDE.md, ralph.sh) from github.com/snarktank/ralph into CWD")
+	cmd.Flags().BoolVar(&detachFlag, "detach", false, "run the loop in the background; see ralph attach and ralph stop")
+	cmd.Flags().BoolVar(&daemonFlag, "daemon", false, "run as the background process started by --detach")
+	cmd.Flags().MarkHidden("daemon")
+}
+
 func run(cmd *cobra.Command, args []string) error {
+	if detachFlag || daemonFlag {
+		// Usage would only bury the daemon's error
+		cmd.SilenceUsage = true
+	}
 	if installClaudeFlag {
 		return installClaude()
 	}
@@ -61,14 +118,24 @@ func run(cmd *cobra.Command, args []string) error {
 	projectDir, _ = filepath.Abs(projectDir)
 
 	// Load config from project dir
-	cfg, err := config.Load(projectDir)
+	cfg, err := loadConfig(projectDir)
 	if err != nil {
-		return fmt.Errorf("loading config: %w", err)
+		return err
+	}
+	for _, m := range cfg.Migrations() {
+		offerMigration(m)
+	}
+	if detachFlag {
+		return startDaemon(projectDir)
+	}
+	// A background ralph is only reachable through its API.
+	if daemonFlag && cfg.Server.Listen == "" {
+		cfg.Set("server.listen", detachListen, "--detach")
 	}
 
-	// Warn if multiple prd.json files exist in the project tree.
+	// Warn if multiple PRD files exist in the project tree.
 	if dupes := findPRDFiles(projectDir); len(dupes) > 1 {
-		fmt.Fprintf(os.Stderr, "Warning: multiple prd.json files found in %s:\n", projectDir)
+		fmt.Fprintf(os.Stderr, "Warning: multiple PRD files found in %s:\n", projectDir)
 		for _, p := range dupes {
 			fmt.Fprintf(os.Stderr, "  %s (%s)\n", p, prdSummary(p))
 		}
@@ -77,33 +144,78 @@ func run(cmd *cobra.Command, args []string) error {
 
 	// Resolve ralph dir
 	ralphDir := resolveRalphDir(ralphDirFlag, projectDir)
+	if ralphDir == "" && cfg.Tracker != "json" {
+		// Other trackers keep no PRD file, so the ralph dir only holds the
+		// prompt and progress.txt.
+		ralphDir = projectDir
+		if fi, err := os.Stat(filepath.Join(projectDir, "scripts", "ralph")); err == nil && fi.IsDir() {
+			ralphDir = filepath.Join(projectDir, "scripts", "ralph")
+		}
+	}
 	if ralphDir == "" {
-		return fmt.Errorf("cannot find ralph directory (no prd.json found). Use --ralph-dir to specify")
+		return fmt.Errorf("cannot find ralph directory (no prd.json, prd.yaml or prd.md found). Use --ralph-dir to specify")
 	}
 	ralphDir, _ = filepath.Abs(ralphDir)
 
-	// Determine tool and max iterations (CLI flags override config)
+	// Determine tool and max iterations
 	agentName := cfg.Agent
-	if toolFlag != "" {
-		agentName = toolFlag
-	}
-	if agentName != "amp" && agentName != "claude" {
-		return fmt.Errorf("invalid tool '%s'. Must be 'amp' or 'claude'", agentName)
+	if !slices.Contains(agent.Names, agentName) {
+		return fmt.Errorf("invalid tool '%s'. Must be one of: %s", agentName, strings.Join(agent.Names, ", "))
 	}
 
 	maxIter := cfg.MaxIterations
-	if maxIterFlag > 0 {
-		maxIter = maxIterFlag
-	}
 	if maxIter <= 0 {
 		maxIter = 10
 	}
 
-	// Load PRD
-	prdPath := filepath.Join(ralphDir, "prd.json")
-	p, err := prd.Load(prdPath)
+	// Set up hooks, webhooks and notifications. A daemon has no terminal
+	// to notify through.
+	if daemonFlag {
+		cfg.Set("notify.terminal", "off", "--detach")
+	}
+	hk := hooks.New(cfg.Hooks, projectDir)
+	wh, err := webhook.New(cfg.Webhooks)
 	if err != nil {
-		return fmt.Errorf("loading PRD: %w", err)
+		return err
+	}
+	nt, err := notify.New(cfg.Notify)
+	if err != nil {
+		return err
+	}
+	if cfg.Guidance != "" && !slices.Contains(tui.GuidanceModes, cfg.Guidance) {
+		return fmt.Errorf("guidance must be one of %s, not %q", strings.Join(tui.GuidanceModes, ", "), cfg.Guidance)
+	}
+
+	// Pull tracker changes into the PRD before reading it
+	if cfg.Sync.OnStart {
+		if _, err := syncPRD(cfg, ralphDir, projectDir, false, os.Stderr); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: sync failed: %v\n", err)
+		}
+	}
+
+	// Open the tracker, validate and load the stories
+	trk, err := tracker.New(cfg.Tracker, tracker.Options{
+		RalphDir:   ralphDir,
+		ProjectDir: projectDir,
+		Settings:   cfg.TrackerOptionsFor(cfg.Tracker),
+	})
+	if err != nil {
+		return err
+	}
+	prdPath := trk.Path()
+	if v, ok := trk.(tracker.Validator); ok {
+		issues, err := v.Validate()
+		if err != nil {
+			return err
+		}
+		printIssues(prdPath, issues)
+		if prd.HasErrors(issues, strictFlag) {
+			return fmt.Errorf("%s is not valid (see `ralph validate`)", prdPath)
+		}
+	}
+	p, err := trk.List()
+	if err != nil {
+		return fmt.Errorf("loading stories from %s tracker: %w", trk.Name(), err)
 	}
 
 	// Branch change detection and archival
@@ -122,21 +234,121 @@ func run(cmd *cobra.Command, args []string) error {
 	session.InitProgressFile(ralphDir)
 
 	// Create session
-	sess := session.NewSession(projectDir, prdPath, agentName, maxIter, p)
+	sess := session.NewSession(projectDir, prdPath, agentName, trk.Name(), maxIter, p)
+	sess.Profile = cfg.Profile
+	// Notes queued in the last session never reached an agent
+	if prev, err := session.Load(projectDir); err == nil && len(prev.PendingGuidance) > 0 {
+		sess.PendingGuidance = prev.PendingGuidance
+		fmt.Fprintf(os.Stderr, "Carrying over %d guidance note(s) from the last session\n", len(prev.PendingGuidance))
+	}
+
+	// Start the HTTP API; it answers once the TUI runs
+	var srv *api.Server
+	if cfg.Server.Listen != "" {
+		if srv, err = api.Listen(cfg.Server.Listen, projectDir); err != nil {
+			return fmt.Errorf("starting the API server: %w", err)
+		}
+		sess.Server = srv.Addr()
+	}
 	sess.Save(projectDir)
 	sess.SaveMeta(projectDir)
+	if daemonFlag {
+		if err := writePIDFile(projectDir); err != nil {
+			srv.Close()
+			return err
+		}
+		defer removePIDFile(projectDir)
+	}
 
-	// Launch TUI
-	return tui.Run(tui.Options{
+	// Run the sessionStart hook; failing hooks, webhooks and notifications
+	// are reported, not fatal
+	warn := func(err error) { fmt.Fprintf(os.Stderr, "Warning: %v\n", err) }
+	wh.OnError(warn)
+	runHook := func(e hooks.Event) {
+		p := hooks.Payload{
+			Event:         e,
+			Time:          time.Now().UTC(),
+			SessionID:     sess.SessionID,
+			ProjectDir:    projectDir,
+			MaxIterations: maxIter,
+			Iteration:     sess.CurrentIteration,
+			Status:        sess.Status,
+		}
+		wh.Send(p)
+		srv.Publish(string(e), p)
+		if err := errors.Join(nt.Notify(p), hk.Run(p)); err != nil {
+			warn(err)
+		}
+	}
+	runHook(hooks.SessionStart)
+
+	// Launch TUI; a daemon has no terminal and writes the output to its
+	// stdout, the log file
+	var outputLog io.Writer
+	if daemonFlag {
+		outputLog = os.Stdout
+	}
+	err = tui.Run(tui.Options{
 		PRD:           p,
-		PRDPath:       prdPath,
+		Tracker:       trk,
 		RalphDir:      ralphDir,
 		ProjectDir:    projectDir,
 		AgentName:     agentName,
-		Model:         modelFlag,
+		Profile:       cfg.Profile,
+		Model:         cfg.Model,
+		AgentOptions:  cfg.AgentOptionsFor(agentName),
 		MaxIterations: maxIter,
 		Session:       sess,
+		StepMode:      cfg.StepMode,
+		Guidance:      cfg.Guidance,
+		Review:        reviewFlag,
+		Hooks:         hk,
+		Webhooks:      wh,
+		Notifier:      nt,
+		Server:        srv,
+		Headless:      daemonFlag,
+		Log:           outputLog,
 	})
+	wh.OnError(warn)
+	runHook(hooks.SessionEnd)
+	srv.Close()
+	if err := wh.Close(webhookDrainTimeout); err != nil {
+		warn(err)
+	}
+	if err == nil && cfg.Sync.OnFinish {
+		if _, err := syncPRD(cfg, ralphDir, projectDir, false, os.Stderr); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: sync failed: %v\n", err)
+		}
+	}
+	return err
+}
+
+// loadConfig loads the layered config for projectDir and applies the CLI
+// flags on top.
+func loadConfig(projectDir string) (*config.Config, error) {
+	cfg, err := config.LoadProfile(projectDir, profileFlag)
+	if err != nil {
+		return nil, fmt.Errorf("loading config: %w", err)
+	}
+	for _, w := range cfg.Warnings() {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+	}
+	if toolFlag != "" {
+		cfg.Set("agent", toolFlag, "--tool")
+	}
+	if modelFlag != "" {
+		cfg.Set("model", modelFlag, "--model")
+	}
+	if maxIterFlag > 0 {
+		cfg.Set("maxIterations", strconv.Itoa(maxIterFlag), "--max-iterations")
+	}
+	if stepFlag {
+		cfg.Set("stepMode", "true", "--step")
+	}
+	if listenFlag != "" {
+		cfg.Set("server.listen", listenFlag, "--listen")
+	}
+	return cfg, nil
 }
 
 // installClaude sparse-clones scripts/ralph from github.com/snarktank/ralph
@@ -214,7 +426,7 @@ func copyDir(src, dst string) error {
 	})
 }
 
-// resolveRalphDir finds the ralph directory containing prd.json.
+// resolveRalphDir finds the ralph directory containing the PRD file.
 func resolveRalphDir(explicit, projectDir string) string {
 	if explicit != "" {
 		return explicit
@@ -242,32 +454,31 @@ func resolveRalphDir(explicit, projectDir string) string {
 }
 
 func hasPRD(dir string) bool {
-	_, err := os.Stat(filepath.Join(dir, "prd.json"))
-	return err == nil
+	return prd.Find(dir) != ""
 }
 
-// prdSummary reads a prd.json and returns a human-readable task count string.
+// prdSummary reads a PRD file and returns a human-readable task count
+// string, or its first validation error.
 func prdSummary(path string) string {
-	data, err := os.ReadFile(path)
+	issues, err := prd.ValidateFile(path)
 	if err != nil {
 		return "unreadable"
 	}
-	if len(bytes.TrimSpace(data)) == 0 {
-		return "empty file"
-	}
-	var p prd.PRD
-	if err := json.Unmarshal(data, &p); err != nil {
-		return "invalid JSON"
+	for _, i := range issues {
+		if i.Severity == prd.SeverityError {
+			return i.Message
+		}
 	}
-	if p.UserStories == nil {
-		return "no userStories field"
+	p, err := prd.Load(path)
+	if err != nil {
+		return "unreadable"
 	}
 	total := p.TotalCount()
 	active := p.RemainingCount()
 	return fmt.Sprintf("%d tasks active, %d tasks total", active, total)
 }
 
-// findPRDFiles returns all prd.json paths found under root, skipping common
+// findPRDFiles returns all PRD file paths found under root, skipping common
 // noise directories (.git, node_modules, vendor).
 func findPRDFiles(root string) []string {
 	skipDirs := map[string]bool{
@@ -283,7 +494,7 @@ func findPRDFiles(root string) []string {
 		if d.IsDir() && skipDirs[d.Name()] {
 			return filepath.SkipDir
 		}
-		if !d.IsDir() && d.Name() == "prd.json" {
+		if !d.IsDir() && prd.IsPRDFile(d.Name()) {
 			found = append(found, path)
 		}
 		return nil
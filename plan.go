@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/zhrkvl/ralph-go/internal/agent"
+	"github.com/zhrkvl/ralph-go/internal/plan"
+	"github.com/zhrkvl/ralph-go/internal/prd"
+)
+
+var (
+	planOutFlag      string
+	planForceFlag    bool
+	planNoReviewFlag bool
+	reviewFlag       bool
+)
+
+func newPlanCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "plan <spec>",
+		Short: "Generate a PRD from a free-form spec using the agent",
+		Long: "Plan runs the configured agent once with a built-in planning prompt and turns\n" +
+			"its answer into a PRD with story IDs, priorities, acceptance criteria and a\n" +
+			"branch name. The PRD is validated and then opened in the Stories view for review;\n" +
+			"press c to start the loop.",
+		Args: cobra.ExactArgs(1),
+		RunE: planSpec,
+	}
+	cmd.Flags().StringVar(&toolFlag, "tool", "", "agent tool to use: amp, claude or scripted (default from config or amp)")
+	cmd.Flags().StringVar(&modelFlag, "model", "", "model to use (passed as --model to the agent)")
+	cmd.Flags().StringVar(&profileFlag, "profile", "", "config profile to use, from [profiles.<name>] in config.toml")
+	cmd.Flags().StringVar(&ralphDirFlag, "ralph-dir", "", "directory to write the PRD to (default: the existing ralph directory or CWD)")
+	cmd.Flags().StringVar(&projectDirFlag, "project-dir", "", "working directory for agent (default: CWD)")
+	cmd.Flags().StringVarP(&planOutFlag, "out", "o", "", "PRD file to write; the extension picks the format (default: <ralph-dir>/prd.json)")
+	cmd.Flags().BoolVar(&planForceFlag, "force", false, "overwrite an existing PRD")
+	cmd.Flags().BoolVar(&planNoReviewFlag, "no-review", false, "write the PRD and exit instead of opening the TUI")
+	return cmd
+}
+
+func planSpec(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+	specPath := args[0]
+	spec, err := os.ReadFile(specPath)
+	if err != nil {
+		return fmt.Errorf("reading spec: %w", err)
+	}
+
+	projectDir := projectDirFlag
+	if projectDir == "" {
+		if projectDir, err = os.Getwd(); err != nil {
+			return fmt.Errorf("getting CWD: %w", err)
+		}
+	}
+	projectDir, _ = filepath.Abs(projectDir)
+
+	cfg, err := loadConfig(projectDir)
+	if err != nil {
+		return err
+	}
+	agentName := cfg.Agent
+	if !slices.Contains(agent.Names, agentName) {
+		return fmt.Errorf("invalid tool '%s'. Must be one of: %s", agentName, strings.Join(agent.Names, ", "))
+	}
+
+	// Decide where the PRD goes before spending an agent run on it.
+	ralphDir := resolveRalphDir(ralphDirFlag, projectDir)
+	if ralphDir == "" {
+		ralphDir = projectDir
+	}
+	outPath := planOutFlag
+	if outPath == "" {
+		if outPath = prd.Find(ralphDir); outPath == "" {
+			outPath = filepath.Join(ralphDir, "prd.json")
+		}
+	}
+	if _, err := os.Stat(outPath); err == nil && !planForceFlag {
+		return fmt.Errorf("%s already exists (use --force to overwrite)", outPath)
+	}
+
+	fmt.Fprintf(os.Stderr, "Planning %s with %s...\n", specPath, agentName)
+	output, err := runPlanner(agent.New(agentName, agent.Options{
+		RalphDir:   ralphDir,
+		ProjectDir: projectDir,
+		Model:      cfg.Model,
+		Settings:   cfg.AgentOptionsFor(agentName),
+		Prompt:     plan.Prompt(string(spec)),
+	}))
+	if err != nil {
+		return err
+	}
+
+	data, err := plan.Extract(output)
+	if err != nil {
+		return err
+	}
+	p, err := plan.Build(data, specPath)
+	if err != nil {
+		return err
+	}
+
+	normalized, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	issues := prd.Validate(normalized)
+	for _, i := range issues {
+		i.Line = 0 // positions refer to the normalized JSON, not a file
+		fmt.Fprintf(os.Stderr, "%s: %s\n", filepath.Base(outPath), i)
+	}
+	if prd.HasErrors(issues, false) {
+		return fmt.Errorf("the generated PRD is not valid")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(outPath), 0o755); err != nil {
+		return err
+	}
+	if err := p.Save(outPath); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %d stories to %s (branch %s)\n", len(p.UserStories), outPath, p.BranchName)
+
+	if planNoReviewFlag {
+		return nil
+	}
+	if !prd.IsPRDFile(filepath.Base(outPath)) {
+		fmt.Fprintf(os.Stderr, "Not opening the TUI: ralph only picks up %s.\n", strings.Join(prd.FileNames, ", "))
+		return nil
+	}
+
+	// Open the loop paused in the Stories view so the plan gets a look
+	// before any story is worked on.
+	ralphDirFlag = filepath.Dir(outPath)
+	projectDirFlag = projectDir
+	reviewFlag = true
+	return run(cmd, nil)
+}
+
+// runPlanner runs the agent once and returns the text of its final
+// messages, without timestamps. Tool calls and other progress are echoed
+// to stderr.
+func runPlanner(a agent.Agent) (string, error) {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	name := a.Name()
+	lines, err := a.Start(ctx)
+	if err != nil {
+		return "", fmt.Errorf("starting %s: %w", name, err)
+	}
+	var out strings.Builder
+	for l := range lines {
+		fmt.Fprintln(os.Stderr, "  "+l.Text)
+		// Streamed deltas are repeated whole in the final message
+		if l.Kind == agent.LineText && !l.Partial {
+			out.WriteString(l.Raw + "\n")
+		}
+	}
+	if _, err := a.Wait(); err != nil {
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+	if ctx.Err() != nil {
+		return "", fmt.Errorf("interrupted")
+	}
+	return out.String(), nil
+}
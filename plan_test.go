@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/zhrkvl/ralph-go/internal/agent"
+	"github.com/zhrkvl/ralph-go/internal/plan"
+)
+
+// TestRunPlannerStreamJSON plays a recorded Claude stream-json run, with
+// partial messages, through runPlanner and builds the PRD it printed.
+func TestRunPlannerStreamJSON(t *testing.T) {
+	data, err := os.ReadFile("testdata/plan-claude.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rec []agent.ReplayLine
+	for _, l := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		rec = append(rec, agent.ReplayLine{Line: l})
+	}
+
+	output, err := runPlanner(agent.NewReplay("claude", rec, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(output, "<prd>"); n != 1 {
+		t.Errorf("output has %d <prd> blocks, want 1:\n%s", n, output)
+	}
+
+	js, err := plan.Extract(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := plan.Build(js, "specs/todo.md")
+	if err != nil {
+		t.Fatalf("%v\noutput:\n%s", err, output)
+	}
+	if len(p.UserStories) != 2 || p.UserStories[1].Title != "List todos" {
+		t.Errorf("stories = %+v", p.UserStories)
+	}
+	if p.BranchName != "ralph/todo-cli" {
+		t.Errorf("branch = %q, want ralph/todo-cli", p.BranchName)
+	}
+}
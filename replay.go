@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/zhrkvl/ralph-go/internal/agent"
+	"github.com/zhrkvl/ralph-go/internal/session"
+	"github.com/zhrkvl/ralph-go/internal/tui"
+)
+
+var replaySpeedFlag float64
+
+func newReplayCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "replay <iteration-log>",
+		Short: "Replay a recorded iteration's raw agent stream through the TUI",
+		Long: "Replay feeds the raw stdout/stderr stream recorded next to an iteration log\n" +
+			"(.ralph-tui/iterations/*.raw.jsonl) back through the output parser and TUI.",
+		Args: cobra.ExactArgs(1),
+		RunE: replay,
+	}
+	cmd.Flags().Float64Var(&replaySpeedFlag, "speed", 1, "playback speed multiplier (0 = no delays)")
+	return cmd
+}
+
+func replay(cmd *cobra.Command, args []string) error {
+	if replaySpeedFlag < 0 {
+		return fmt.Errorf("invalid --speed %v: must be >= 0", replaySpeedFlag)
+	}
+
+	header, records, err := session.ReadRawStream(args[0])
+	if err != nil {
+		return err
+	}
+
+	lines := make([]agent.ReplayLine, len(records))
+	for i, r := range records {
+		lines[i] = agent.ReplayLine{
+			Offset: time.Duration(r.OffsetMs) * time.Millisecond,
+			Line:   r.Line,
+		}
+	}
+
+	return tui.Run(tui.Options{
+		AgentName:     header.Agent,
+		MaxIterations: 1,
+		Replay:        lines,
+		ReplaySpeed:   replaySpeedFlag,
+	})
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/zhrkvl/ralph-go/internal/api"
+	"github.com/zhrkvl/ralph-go/internal/session"
+)
+
+var statusJSONFlag bool
+
+func newStatusCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show the session running in, or last run in, a project",
+		Long: "Status asks a running ralph through its API for the live state of the loop. When\n" +
+			"ralph is not running, or was started without the API, it shows the session last\n" +
+			"saved in .ralph-tui/session.json.",
+		Args: cobra.NoArgs,
+		RunE: showStatus,
+	}
+	cmd.Flags().BoolVar(&statusJSONFlag, "json", false, "print the status as JSON, as GET /status returns it")
+	cmd.Flags().StringVar(&projectDirFlag, "project-dir", "", "project directory (default: CWD)")
+	return cmd
+}
+
+// finished lists the session statuses of a loop that has ended.
+var finished = []string{"completed", "failed", "interrupted"}
+
+// liveSession loads the saved session of projectDir and, if its ralph is
+// still serving the API, the live status. st is nil when ralph cannot be
+// reached.
+func liveSession(projectDir string) (sess *session.Session, c *api.Client, st *api.Status, err error) {
+	sess, err = session.Load(projectDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, nil, fmt.Errorf("no ralph session in %s", projectDir)
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if sess.Server == "" {
+		return sess, nil, nil, nil
+	}
+	c = api.NewClient(sess.Server, projectDir)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if st, err = c.Status(ctx); err != nil || st.Session == nil || st.Session.SessionID != sess.SessionID {
+		return sess, c, nil, nil
+	}
+	return st.Session, c, st, nil
+}
+
+func showStatus(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+	projectDir := projectDirFlag
+	if projectDir == "" {
+		var err error
+		if projectDir, err = os.Getwd(); err != nil {
+			return fmt.Errorf("getting CWD: %w", err)
+		}
+	}
+	projectDir, _ = filepath.Abs(projectDir)
+
+	sess, _, st, err := liveSession(projectDir)
+	if err != nil {
+		return err
+	}
+	live := st != nil
+	if !live {
+		st = &api.Status{
+			Session:       sess,
+			State:         sess.Status,
+			Iteration:     sess.CurrentIteration,
+			MaxIterations: sess.MaxIterations,
+		}
+		if !slices.Contains(finished, sess.Status) && sess.Server != "" {
+			st.State = "stopped"
+		}
+		if len(sess.ActiveTaskIDs) > 0 && sess.TrackerState != nil {
+			for _, t := range sess.TrackerState.Tasks {
+				if t.ID == sess.ActiveTaskIDs[0] {
+					st.CurrentStory = &api.StoryRef{ID: t.ID, Title: t.Title}
+				}
+			}
+		}
+	}
+	if statusJSONFlag {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(st)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	source := "live"
+	if !live {
+		source = "saved"
+	}
+	fmt.Fprintf(w, "Session\t%s (%s)\n", sess.SessionID, source)
+	state := st.State
+	if state == "stopped" {
+		state = fmt.Sprintf("stopped (ralph is not running; last saved as %s)", sess.Status)
+	}
+	if st.BlockedReason != "" {
+		state += ": " + st.BlockedReason
+	}
+	fmt.Fprintf(w, "State\t%s\n", state)
+	agent := sess.AgentPlugin
+	if sess.Profile != "" {
+		agent += " [" + sess.Profile + "]"
+	}
+	fmt.Fprintf(w, "Agent\t%s\n", agent)
+	fmt.Fprintf(w, "Iteration\t%d/%d\n", st.Iteration, st.MaxIterations)
+	if st.CurrentStory != nil {
+		fmt.Fprintf(w, "Story\t%s %s\n", st.CurrentStory.ID, st.CurrentStory.Title)
+	}
+	if ts := sess.TrackerState; ts != nil {
+		fmt.Fprintf(w, "Stories\t%d/%d passing\n", sess.TasksCompleted, ts.TotalTasks)
+	}
+	started := sess.StartedAt.Local().Format("2006-01-02 15:04")
+	switch {
+	case st.State == "stopped":
+		// Saved at iteration boundaries only, so the time it stopped
+		// is not known.
+		fmt.Fprintf(w, "Started\t%s (last saved %s)\n", started, sess.UpdatedAt.Local().Format("15:04:05"))
+	case slices.Contains(finished, sess.Status):
+		fmt.Fprintf(w, "Elapsed\t%s (started %s)\n", session.FormatDuration(sess.UpdatedAt.Sub(sess.StartedAt)), started)
+	default:
+		fmt.Fprintf(w, "Elapsed\t%s (started %s)\n", session.FormatDuration(time.Since(sess.StartedAt)), started)
+	}
+	if sess.CostUSD > 0 {
+		fmt.Fprintf(w, "Cost\t$%.4f\n", sess.CostUSD)
+	}
+	if n := len(st.PendingGuidance); n > 0 {
+		fmt.Fprintf(w, "Guidance\t%d note(s) queued\n", n)
+	}
+	if live {
+		fmt.Fprintf(w, "API\t%s\n", sess.Server)
+	}
+	return w.Flush()
+}
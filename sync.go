@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/zhrkvl/ralph-go/internal/config"
+	"github.com/zhrkvl/ralph-go/internal/tracker"
+)
+
+var (
+	syncTrackerFlag string
+	syncDryRunFlag  bool
+)
+
+func newSyncCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Reconcile the PRD with an external tracker",
+		Long: "Sync keeps the PRD file the agent edits in step with another tracker (e.g. GitHub\n" +
+			"issues). New items are pulled into the PRD, stories marked as passing and notes\n" +
+			"added in the PRD are pushed, and stories changed on both sides since the last sync\n" +
+			"are reported as conflicts and left alone. Sync state is kept in .ralph-tui/sync.json.",
+		Args: cobra.NoArgs,
+		RunE: syncCmd,
+	}
+	cmd.Flags().StringVar(&syncTrackerFlag, "tracker", "", "tracker to sync with (default: tracker under [sync] in config)")
+	cmd.Flags().BoolVar(&syncDryRunFlag, "dry-run", false, "report what would change without writing anything")
+	cmd.Flags().StringVar(&profileFlag, "profile", "", "config profile to use, from [profiles.<name>] in config.toml")
+	cmd.Flags().StringVar(&ralphDirFlag, "ralph-dir", "", "directory containing the PRD")
+	cmd.Flags().StringVar(&projectDirFlag, "project-dir", "", "project directory (default: CWD)")
+	return cmd
+}
+
+func syncCmd(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+	projectDir := projectDirFlag
+	if projectDir == "" {
+		var err error
+		if projectDir, err = os.Getwd(); err != nil {
+			return fmt.Errorf("getting CWD: %w", err)
+		}
+	}
+	projectDir, _ = filepath.Abs(projectDir)
+
+	cfg, err := loadConfig(projectDir)
+	if err != nil {
+		return err
+	}
+	if syncTrackerFlag != "" {
+		cfg.Set("sync.tracker", syncTrackerFlag, "--tracker")
+	}
+	ralphDir := resolveRalphDir(ralphDirFlag, projectDir)
+	if ralphDir == "" {
+		return fmt.Errorf("cannot find ralph directory (no PRD file found). Use --ralph-dir to specify")
+	}
+	ralphDir, _ = filepath.Abs(ralphDir)
+
+	report, err := syncPRD(cfg, ralphDir, projectDir, syncDryRunFlag, os.Stdout)
+	if err != nil {
+		return err
+	}
+	if n := report.Conflicts(); n > 0 {
+		return fmt.Errorf("%d conflict(s); edit the story on one side to match the other and sync again", n)
+	}
+	return nil
+}
+
+// syncPRD syncs the PRD in ralphDir with the tracker under [sync] and
+// prints what changed to w. The sync state is saved unless dryRun is set.
+func syncPRD(cfg *config.Config, ralphDir, projectDir string, dryRun bool, w io.Writer) (*tracker.SyncReport, error) {
+	if cfg.Sync.Tracker == "" {
+		return nil, fmt.Errorf("no tracker to sync with: set tracker under [sync] in config.toml or pass --tracker")
+	}
+	if cfg.Tracker != "json" {
+		return nil, fmt.Errorf("sync keeps the PRD file up to date and needs tracker = \"json\", not %q", cfg.Tracker)
+	}
+	if cfg.Sync.Tracker == "json" {
+		return nil, fmt.Errorf("cannot sync the PRD with itself: set [sync] tracker to another tracker")
+	}
+
+	local, err := tracker.New("json", tracker.Options{
+		RalphDir:   ralphDir,
+		ProjectDir: projectDir,
+		Settings:   cfg.TrackerOptionsFor("json"),
+	})
+	if err != nil {
+		return nil, err
+	}
+	remote, err := tracker.New(cfg.Sync.Tracker, tracker.Options{
+		RalphDir:   ralphDir,
+		ProjectDir: projectDir,
+		Settings:   cfg.TrackerOptionsFor(cfg.Sync.Tracker),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := tracker.LoadSyncState(projectDir)
+	if err != nil {
+		return nil, err
+	}
+	report, next, err := tracker.Sync(local, remote, state, dryRun)
+	if report != nil {
+		for _, a := range report.Actions {
+			fmt.Fprintln(w, a)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(report.Actions) == 0 {
+		fmt.Fprintf(w, "%s is in sync with %s\n", local.Path(), remote.Name())
+	}
+	if dryRun {
+		return report, nil
+	}
+	if err := next.Save(projectDir); err != nil {
+		return nil, fmt.Errorf("saving sync state: %w", err)
+	}
+	return report, nil
+}
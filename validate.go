@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/zhrkvl/ralph-go/internal/prd"
+)
+
+var (
+	strictFlag      bool
+	printSchemaFlag bool
+)
+
+func newValidateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate [path]",
+		Short: "Check the PRD for errors and suspicious content",
+		Long: "Validate checks a PRD file (default: the one ralph would use) for syntax and type\n" +
+			"errors, missing or duplicate story IDs and empty titles, and warns about unknown\n" +
+			"fields, stories without acceptance criteria and priority collisions.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: validate,
+	}
+	cmd.Flags().BoolVar(&strictFlag, "strict", false, "treat warnings as errors")
+	cmd.Flags().BoolVar(&printSchemaFlag, "schema", false, "print the prd.json JSON Schema and exit")
+	cmd.Flags().StringVar(&ralphDirFlag, "ralph-dir", "", "directory containing the PRD")
+	return cmd
+}
+
+func validate(cmd *cobra.Command, args []string) error {
+	if printSchemaFlag {
+		os.Stdout.Write(prd.Schema)
+		return nil
+	}
+
+	var path string
+	if len(args) > 0 {
+		path = args[0]
+	} else {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("getting CWD: %w", err)
+		}
+		ralphDir := resolveRalphDir(ralphDirFlag, cwd)
+		if ralphDir == "" {
+			return fmt.Errorf("cannot find ralph directory (no PRD file found). Pass a path or use --ralph-dir")
+		}
+		if path = prd.Find(ralphDir); path == "" {
+			return fmt.Errorf("no PRD file in %s", ralphDir)
+		}
+	}
+
+	issues, err := prd.ValidateFile(path)
+	if err != nil {
+		return err
+	}
+	printIssues(path, issues)
+	if prd.HasErrors(issues, strictFlag) {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("%s is not valid", path)
+	}
+	if len(issues) == 0 {
+		fmt.Printf("%s: ok\n", path)
+	}
+	return nil
+}
+
+// printIssues prints issues in the file:line:col: form editors understand.
+func printIssues(path string, issues []prd.Issue) {
+	for _, i := range issues {
+		if i.Line == 0 {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", path, i)
+		} else {
+			fmt.Fprintf(os.Stderr, "%s:%s\n", path, i)
+		}
+	}
+}